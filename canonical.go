@@ -0,0 +1,123 @@
+package dom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// canonicalTextEscaper escapes the text in canonical form.
+	canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#13;")
+
+	// canonicalAttrEscaper escapes the attribute value in canonical form.
+	canonicalAttrEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;", "\r", "&#13;")
+)
+
+// CanonicalOptions is the options for RenderCanonicalWithOptions.
+type CanonicalOptions struct {
+	// Cascade is used to decide where whitespace is preformatted.
+	// If it's nil, only the inline style is used.
+	Cascade *Cascade
+}
+
+// RenderCanonical renders the node and its descendants in canonical form, so
+// the same content always produces the same HTML regardless of how it's
+// written in the source. In canonical form, attributes are sorted by their
+// name and always double-quoted, all tags are written explicitly, only the
+// characters that must be escaped are written as character reference (using
+// the same reference for the same character), and whitespace outside the
+// preformatted context is collapsed the same way as in RenderMinified.
+func RenderCanonical(w io.Writer, node *html.Node) error {
+	return RenderCanonicalWithOptions(w, node, CanonicalOptions{})
+}
+
+// RenderCanonicalWithOptions is like RenderCanonical, but
+// renders the node following the specified options.
+func RenderCanonicalWithOptions(w io.Writer, node *html.Node, opts CanonicalOptions) error {
+	if node == nil {
+		return nil
+	}
+
+	c := canonicalRenderer{
+		rw:    &renderWriter{w: w},
+		texts: collapseWhitespace(node, cascadeFor(opts.Cascade)),
+	}
+
+	c.writeNode(node)
+	return c.rw.err
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the node in its canonical
+// form, which is useful to compare or deduplicate documents. Returns
+// empty string if the node can't be rendered.
+func Hash(node *html.Node) string {
+	return HashWithOptions(node, CanonicalOptions{})
+}
+
+// HashWithOptions is like Hash, but renders the
+// canonical form following the specified options.
+func HashWithOptions(node *html.Node, opts CanonicalOptions) string {
+	hash := sha256.New()
+	if err := RenderCanonicalWithOptions(hash, node, opts); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type canonicalRenderer struct {
+	rw    *renderWriter
+	texts map[*html.Node]string
+}
+
+func (c *canonicalRenderer) writeNode(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		switch text, collapsed := c.texts[node]; {
+		case collapsed:
+			c.rw.writeString(canonicalTextEscaper.Replace(text))
+		case isRawTextElement(node.Parent):
+			c.rw.writeString(node.Data)
+		default:
+			c.rw.writeString(canonicalTextEscaper.Replace(node.Data))
+		}
+
+	case html.DocumentNode:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			c.writeNode(child)
+		}
+
+	case html.ElementNode:
+		attrs := make([]html.Attribute, len(node.Attr))
+		copy(attrs, node.Attr)
+		sort.SliceStable(attrs, func(i, j int) bool {
+			return attributeQualifiedName(attrs[i]) < attributeQualifiedName(attrs[j])
+		})
+
+		c.rw.writeString("<" + node.Data)
+		for _, attr := range attrs {
+			c.rw.writeString(" " + attributeQualifiedName(attr) + `="` + canonicalAttrEscaper.Replace(attr.Val) + `"`)
+		}
+		c.rw.writeString(">")
+
+		if IsVoidElement(node) && node.Namespace == "" {
+			return
+		}
+
+		if needsLeadingNewline(node) {
+			c.rw.writeString("\n")
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			c.writeNode(child)
+		}
+		c.rw.writeString(endTag(node))
+
+	default:
+		c.rw.writeVerbatim(node)
+	}
+}
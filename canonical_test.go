@@ -0,0 +1,110 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestRenderCanonical(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "sorted attributes",
+		htmlSource: `<div title='x' id=main class="a b"></div>`,
+		want:       `<div class="a b" id="main" title="x"></div>`,
+	}, {
+		name:       "character references",
+		htmlSource: `<p title="&quot;q&quot; &amp; &#39;s&#39;">&lt;a&gt; &amp; &#x27;s&#39; &apos;&nbsp;&copy;</p>`,
+		want:       "<p title=\"&quot;q&quot; &amp; 's'\">&lt;a&gt; &amp; 's' ' ©</p>",
+	}, {
+		name:       "whitespace",
+		htmlSource: "<div>\n  Hello   <b> world </b>\n</div>\n<pre>  keep\n  this </pre>",
+		want:       "<div>Hello <b>world</b></div><pre>  keep\n  this </pre>",
+	}, {
+		name:       "optional and void tags",
+		htmlSource: `<ul><li>One<li>Two</ul><p>Text<br/><img src=a.png></p>`,
+		want:       `<ul><li>One</li><li>Two</li></ul><p>Text<br><img src="a.png"></p>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Fatalf("RenderCanonical(), failed to parse: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := dom.RenderCanonical(&sb, doc); err != nil {
+				t.Fatalf("RenderCanonical() error = %v", err)
+			}
+
+			if got := sb.String(); got != "<body>"+tt.want+"</body>" {
+				t.Errorf("RenderCanonical() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("custom cascade", func(t *testing.T) {
+		doc, err := dom.Parse(strings.NewReader("<style>.code { white-space: pre }</style><div class=\"code\">a\n  b</div>"))
+		if err != nil {
+			t.Fatalf("RenderCanonical(), failed to parse: %v", err)
+		}
+
+		var sb strings.Builder
+		div := dom.QuerySelector(doc, "div")
+		opts := dom.CanonicalOptions{Cascade: dom.NewCascade(doc, dom.CascadeOptions{})}
+		if err := dom.RenderCanonicalWithOptions(&sb, div, opts); err != nil {
+			t.Fatalf("RenderCanonicalWithOptions() error = %v", err)
+		}
+
+		if got, want := sb.String(), "<div class=\"code\">a\n  b</div>"; got != want {
+			t.Errorf("RenderCanonicalWithOptions() = %q, want %q", got, want)
+		}
+
+		if dom.HashWithOptions(div, opts) == dom.Hash(div) {
+			t.Errorf("HashWithOptions() = Hash(), want different hash")
+		}
+	})
+}
+
+func TestHash(t *testing.T) {
+	hashOf := func(htmlSource string) string {
+		doc, err := dom.Parse(strings.NewReader(htmlSource))
+		if err != nil {
+			t.Fatalf("Hash(), failed to parse: %v", err)
+		}
+		return dom.Hash(doc)
+	}
+
+	original := hashOf(`<html><body><p class="intro" id="p1">Hello &amp; welcome</p></body></html>`)
+	if len(original) != 64 {
+		t.Errorf("Hash() = %q, want 64 hex characters", original)
+	}
+
+	sameContent := []string{
+		`<p id=p1 class=intro>Hello &#38; welcome</p>`,
+		"<html>\n<body>\n  <p id='p1' class='intro'>\n    Hello &AMP; welcome\n  </p>\n</body>\n</html>",
+	}
+
+	for _, htmlSource := range sameContent {
+		if got := hashOf(htmlSource); got != original {
+			t.Errorf("Hash(%q) = %v, want %v", htmlSource, got, original)
+		}
+	}
+
+	differentContent := []string{
+		`<p id="p1" class="intro">Hello &amp; welcome!</p>`,
+		`<p id="p2" class="intro">Hello &amp; welcome</p>`,
+		`<pre id="p1" class="intro">Hello &amp; welcome</pre>`,
+	}
+
+	for _, htmlSource := range differentContent {
+		if got := hashOf(htmlSource); got == original {
+			t.Errorf("Hash(%q) = %v, want different hash", htmlSource, got)
+		}
+	}
+}
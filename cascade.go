@@ -0,0 +1,482 @@
+package dom
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// cascadeProperties is the list of CSS properties that computed by Cascade,
+// along with their initial value and whether they are inherited or not.
+var cascadeProperties = map[string]struct {
+	initial   string
+	inherited bool
+}{
+	"display":     {"inline", false},
+	"visibility":  {"visible", true},
+	"white-space": {"normal", true},
+	"content":     {"normal", false},
+}
+
+// uaDisplay is the default display of HTML elements, taken from the
+// rendering section of HTML specification. Elements that not listed
+// here are displayed as inline.
+var uaDisplay = map[string]string{
+	"area": "none", "base": "none", "basefont": "none", "datalist": "none",
+	"head": "none", "link": "none", "meta": "none", "noembed": "none",
+	"noframes": "none", "noscript": "none", "param": "none", "rp": "none",
+	"script": "none", "style": "none", "template": "none", "title": "none",
+
+	"html": "block", "body": "block", "address": "block", "blockquote": "block",
+	"center": "block", "dialog": "block", "div": "block", "figure": "block",
+	"figcaption": "block", "footer": "block", "form": "block", "header": "block",
+	"hr": "block", "legend": "block", "listing": "block", "main": "block",
+	"p": "block", "plaintext": "block", "pre": "block", "search": "block",
+	"xmp": "block", "details": "block", "article": "block", "aside": "block",
+	"h1": "block", "h2": "block", "h3": "block", "h4": "block", "h5": "block",
+	"h6": "block", "hgroup": "block", "nav": "block", "section": "block",
+	"dir": "block", "dd": "block", "dl": "block", "dt": "block", "menu": "block",
+	"ol": "block", "ul": "block", "fieldset": "block", "optgroup": "block",
+	"frameset": "block", "frame": "block",
+
+	"li": "list-item", "summary": "list-item",
+
+	"table": "table", "caption": "table-caption", "colgroup": "table-column-group",
+	"col": "table-column", "thead": "table-header-group", "tbody": "table-row-group",
+	"tfoot": "table-footer-group", "tr": "table-row", "td": "table-cell",
+	"th": "table-cell",
+
+	"ruby": "ruby", "rt": "ruby-text",
+
+	"input": "inline-block", "button": "inline-block", "select": "inline-block",
+	"textarea": "inline-block", "meter": "inline-block", "progress": "inline-block",
+}
+
+// uaWhiteSpace is the default white-space of HTML elements.
+var uaWhiteSpace = map[string]string{
+	"pre":       "pre",
+	"listing":   "pre",
+	"xmp":       "pre",
+	"plaintext": "pre",
+	"textarea":  "pre-wrap",
+	"nobr":      "nowrap",
+}
+
+// ComputedValues is the computed value of several CSS properties that
+// affect the visibility and the text rendering of a node.
+type ComputedValues struct {
+	Display    string
+	Visibility string
+	WhiteSpace string
+	Content    string
+}
+
+// GetPropertyValue returns the computed value of the specified property,
+// or empty string if the property is not computed by Cascade.
+func (cv ComputedValues) GetPropertyValue(name string) string {
+	switch normalizeCSSPropertyName(name) {
+	case "display":
+		return cv.Display
+	case "visibility":
+		return cv.Visibility
+	case "white-space":
+		return cv.WhiteSpace
+	case "content":
+		return cv.Content
+	default:
+		return ""
+	}
+}
+
+// CascadeOptions is the options for building Cascade.
+type CascadeOptions struct {
+	// ResolveStylesheet is used to fetch the content of external stylesheet,
+	// i.e. from <link rel="stylesheet"> and @import rule. It receives the URL
+	// as written in the document, and returns false if the stylesheet is not
+	// available. If it's nil, the external stylesheets are ignored.
+	ResolveStylesheet func(href string) (string, bool)
+}
+
+// Cascade is a lightweight CSS cascade engine. It collects the style rules
+// from <style> elements (and optionally the linked stylesheets), matches them
+// with cascadia, then resolves the specificity and inheritance for a small set
+// of properties, i.e. display, visibility, white-space and content. Rules that
+// inside @media are only used if the media query is unconditional for screen.
+//
+// Functions that compute the style without a Cascade, e.g. InnerText, only use
+// the user agent stylesheet and the inline style of the node, its ancestors and
+// its descendants, so they never search the whole document. To use the
+// stylesheets as well, pass a Cascade in their options. Since the computed
+// values are cached, create a new Cascade after the document is modified.
+type Cascade struct {
+	rules []cssRule
+	cache map[*html.Node]ComputedValues
+}
+
+// cssRule is a style rule with a single selector.
+type cssRule struct {
+	selector     cascadia.Sel
+	specificity  cascadia.Specificity
+	order        int
+	declarations []cssDeclaration
+}
+
+// NewCascade creates a new Cascade using the stylesheets in the document
+// which contains the specified root.
+func NewCascade(root *html.Node, opts CascadeOptions) *Cascade {
+	c := &Cascade{cache: make(map[*html.Node]ComputedValues)}
+
+	var finder func(*html.Node)
+	finder = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Namespace == "" {
+			switch n.Data {
+			case "style":
+				if isCSSType(GetAttribute(n, "type")) && mediaMatches(GetAttribute(n, "media")) {
+					c.addStylesheet(TextContent(n), opts, 0)
+				}
+				return
+			case "link":
+				rel := NewTokenList(n, "rel")
+				if opts.ResolveStylesheet != nil && rel.Contains("stylesheet") &&
+					!rel.Contains("alternate") && mediaMatches(GetAttribute(n, "media")) {
+					if css, ok := opts.ResolveStylesheet(strings.TrimSpace(GetAttribute(n, "href"))); ok {
+						c.addStylesheet(css, opts, 0)
+					}
+				}
+				return
+			case "template":
+				return
+			}
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			finder(child)
+		}
+	}
+
+	finder(root)
+	return c
+}
+
+// ComputedStyle returns the computed values for the specified node using the
+// <style> elements in its document. For non-element node, the inherited values
+// from its parent are returned. If you need to compute many nodes or to use
+// external stylesheets, create a Cascade and use its method instead.
+func ComputedStyle(node *html.Node) ComputedValues {
+	return NewCascade(GetRootNode(node), CascadeOptions{}).ComputedStyle(node)
+}
+
+// cascadeFor returns the cascade that passed in the options, or a cascade
+// without any stylesheet if it's nil.
+func cascadeFor(cascade *Cascade) *Cascade {
+	if cascade != nil {
+		return cascade
+	}
+	return &Cascade{cache: make(map[*html.Node]ComputedValues)}
+}
+
+// ComputedStyle returns the computed values for the specified node. For
+// non-element node, the inherited values from its parent are returned.
+func (c *Cascade) ComputedStyle(node *html.Node) ComputedValues {
+	if cv, cached := c.cache[node]; cached {
+		return cv
+	}
+
+	var parentValues *ComputedValues
+	if parent := ParentElement(node); parent != nil {
+		pv := c.ComputedStyle(parent)
+		parentValues = &pv
+	}
+
+	var cv ComputedValues
+	if node.Type == html.ElementNode {
+		cv = c.compute(node, parentValues)
+	} else {
+		cv = c.compute(nil, parentValues)
+	}
+
+	c.cache[node] = cv
+	return cv
+}
+
+// compute computes the values for the element. If element is nil,
+// the values are computed as if there are no declarations at all.
+func (c *Cascade) compute(element *html.Node, parentValues *ComputedValues) ComputedValues {
+	declared := make(map[string]string)
+	if element != nil {
+		declared = c.declaredValues(element)
+	}
+
+	resolve := func(name string) string {
+		prop := cascadeProperties[name]
+		value, exist := declared[name]
+
+		var parentValue string
+		if parentValues != nil {
+			parentValue = parentValues.GetPropertyValue(name)
+		} else {
+			parentValue = prop.initial
+		}
+
+		switch {
+		case !exist:
+			if prop.inherited {
+				return parentValue
+			}
+			return prop.initial
+		case value == "inherit":
+			return parentValue
+		case value == "initial":
+			return prop.initial
+		case value == "unset":
+			if prop.inherited {
+				return parentValue
+			}
+			return prop.initial
+		default:
+			return value
+		}
+	}
+
+	return ComputedValues{
+		Display:    resolve("display"),
+		Visibility: resolve("visibility"),
+		WhiteSpace: resolve("white-space"),
+		Content:    resolve("content"),
+	}
+}
+
+// declaredValues returns the cascaded value of each property for the element,
+// following the order: user agent, author normal, inline normal, author
+// important, then inline important.
+func (c *Cascade) declaredValues(element *html.Node) map[string]string {
+	declared := make(map[string]string)
+
+	// User agent stylesheet
+	if element.Namespace == "" {
+		if display, exist := uaDisplay[element.Data]; exist {
+			declared["display"] = display
+		}
+
+		if whiteSpace, exist := uaWhiteSpace[element.Data]; exist {
+			declared["white-space"] = whiteSpace
+		}
+
+		if (element.Data == "td" || element.Data == "th") && HasAttribute(element, "nowrap") {
+			declared["white-space"] = "nowrap"
+		}
+
+		if HasAttribute(element, "hidden") {
+			declared["display"] = "none"
+		}
+	}
+
+	// Author stylesheets, sorted by specificity then order of appearance
+	var matched []cssRule
+	for _, rule := range c.rules {
+		if rule.selector.Match(element) {
+			matched = append(matched, rule)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity.Less(matched[j].specificity)
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	var inline []cssDeclaration
+	if HasAttribute(element, "style") {
+		inline = parseCSSDeclarations(GetAttribute(element, "style"))
+	}
+
+	for _, important := range []bool{false, true} {
+		for _, rule := range matched {
+			applyCSSDeclarations(declared, rule.declarations, important)
+		}
+		applyCSSDeclarations(declared, inline, important)
+	}
+
+	return declared
+}
+
+// addStylesheet parses the stylesheet and adds its rules into the cascade.
+// Depth is used to prevent infinite loop on recursive @import.
+func (c *Cascade) addStylesheet(css string, opts CascadeOptions, depth int) {
+	if depth > 8 {
+		return
+	}
+
+	css = stripCSSComments(css)
+	for {
+		css = strings.TrimLeft(css, " \t\n\r\f")
+		if css == "" {
+			return
+		}
+
+		// Find the end of current statement, which is either ";" for
+		// statement at-rule or "{...}" for rule with block.
+		semicolonIdx := indexCSS(css, ';')
+		braceIdx := indexCSS(css, '{')
+		if braceIdx < 0 || (semicolonIdx >= 0 && semicolonIdx < braceIdx) {
+			if semicolonIdx < 0 {
+				return
+			}
+
+			if strings.HasPrefix(css, "@") {
+				c.addAtStatement(css[:semicolonIdx], opts, depth)
+			}
+
+			css = css[semicolonIdx+1:]
+			continue
+		}
+
+		prelude := strings.TrimSpace(css[:braceIdx])
+		blockEnd := indexCSS(css[braceIdx+1:], '}')
+		if blockEnd < 0 {
+			blockEnd = len(css) - braceIdx - 1
+		}
+
+		block := css[braceIdx+1 : braceIdx+1+blockEnd]
+		if braceIdx+2+blockEnd <= len(css) {
+			css = css[braceIdx+2+blockEnd:]
+		} else {
+			css = ""
+		}
+
+		if strings.HasPrefix(prelude, "@") {
+			name, condition := splitAtRule(prelude)
+			switch {
+			case name == "media" && mediaMatches(condition):
+				c.addStylesheet(block, opts, depth+1)
+			case name == "supports":
+				c.addStylesheet(block, opts, depth+1)
+			}
+			continue
+		}
+
+		c.addRule(prelude, block)
+	}
+}
+
+// addAtStatement handles at-rule statement that doesn't have block.
+// Currently only @import is supported.
+func (c *Cascade) addAtStatement(statement string, opts CascadeOptions, depth int) {
+	name, params := splitAtRule(statement)
+	if name != "import" || opts.ResolveStylesheet == nil {
+		return
+	}
+
+	// Extract the URL, which is either a string or url() function
+	var href string
+	switch {
+	case strings.HasPrefix(params, `"`) || strings.HasPrefix(params, `'`):
+		end := strings.IndexByte(params[1:], params[0])
+		if end < 0 {
+			return
+		}
+		href, params = params[1:end+1], params[end+2:]
+	case strings.HasPrefix(strings.ToLower(params), "url("):
+		end := strings.IndexByte(params, ')')
+		if end < 0 {
+			return
+		}
+		href = strings.Trim(strings.TrimSpace(params[4:end]), `"'`)
+		params = params[end+1:]
+	default:
+		return
+	}
+
+	if !mediaMatches(params) {
+		return
+	}
+
+	if css, ok := opts.ResolveStylesheet(href); ok {
+		c.addStylesheet(css, opts, depth+1)
+	}
+}
+
+// addRule adds a style rule. Each selector in the selector list is added
+// as separate rule, since they might have different specificity.
+func (c *Cascade) addRule(selectors string, block string) {
+	var declarations []cssDeclaration
+	for _, decl := range parseCSSDeclarations(block) {
+		if _, supported := cascadeProperties[decl.name]; supported {
+			declarations = append(declarations, decl)
+		}
+	}
+
+	if len(declarations) == 0 {
+		return
+	}
+
+	for _, selector := range splitCSS(selectors, ',') {
+		// Invalid or unsupported selector is skipped, e.g. selector
+		// with pseudo-element or dynamic pseudo-class like :hover
+		sel, err := cascadia.Parse(strings.TrimSpace(selector))
+		if err != nil {
+			continue
+		}
+
+		c.rules = append(c.rules, cssRule{
+			selector:     sel,
+			specificity:  sel.Specificity(),
+			order:        len(c.rules),
+			declarations: declarations,
+		})
+	}
+}
+
+// applyCSSDeclarations puts the value of supported declarations with the
+// specified importance into the declared values.
+func applyCSSDeclarations(declared map[string]string, declarations []cssDeclaration, important bool) {
+	for _, decl := range declarations {
+		if _, supported := cascadeProperties[decl.name]; !supported || decl.important != important {
+			continue
+		}
+
+		value := decl.value
+		if decl.name != "content" {
+			value = strings.ToLower(value)
+		}
+		declared[decl.name] = value
+	}
+}
+
+// splitAtRule splits the prelude of at-rule into its lowercased
+// name (without "@") and its parameters.
+func splitAtRule(prelude string) (string, string) {
+	prelude = strings.TrimPrefix(prelude, "@")
+	idx := strings.IndexFunc(prelude, isASCIIWhitespace)
+	if idx < 0 {
+		return strings.ToLower(prelude), ""
+	}
+	return strings.ToLower(prelude[:idx]), strings.TrimSpace(prelude[idx:])
+}
+
+// mediaMatches check whether the media query list applies to screen. Since we
+// can't evaluate media features (e.g. width), only unconditional query for
+// all or screen media is considered matching.
+func mediaMatches(mediaList string) bool {
+	if strings.TrimSpace(mediaList) == "" {
+		return true
+	}
+
+	for _, query := range splitCSS(mediaList, ',') {
+		query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+		query = strings.TrimPrefix(query, "only ")
+		if query == "all" || query == "screen" {
+			return true
+		}
+	}
+	return false
+}
+
+// isCSSType check whether the type attribute of <style> is CSS.
+func isCSSType(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	return mimeType == "" || mimeType == "text/css"
+}
@@ -0,0 +1,149 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestComputedStyle(t *testing.T) {
+	htmlSource := `<html><head>
+		<style>
+			.hidden { display: none }
+			#main p { visibility: hidden }
+			#main p.shown { visibility: visible }
+			p.shown { visibility: collapse }
+			.important { display: none !important }
+			code, .pre-like { white-space: pre-wrap }
+			.quote::before { content: "x" }
+			.quote { content: "\201C" }
+			span:hover { display: none }
+			@media print { .print-hidden { display: none } }
+			@media screen { .screen-hidden { display: none } }
+			@import "ignored.css";
+		</style>
+		<style media="print">.print { display: none }</style>
+		<style type="text/less">.less { display: none }</style>
+	</head><body>
+		<div id="main">
+			<p id="p1">Hidden paragraph <span id="s1">and span</span></p>
+			<p id="p2" class="shown">Shown paragraph</p>
+		</div>
+		<div id="d1" class="hidden">Hidden div</div>
+		<div id="d2" class="important" style="display: block">Important div</div>
+		<div id="d3" class="hidden" style="display: block">Inline div</div>
+		<div id="d4" class="print-hidden screen-hidden">Media div</div>
+		<div id="d5" class="print less">Not hidden</div>
+		<pre id="pre1">Pre <code id="code1">code</code></pre>
+		<span id="s2" class="pre-like">Span</span>
+		<q id="q1" class="quote">Quote</q>
+		<p id="p3" hidden>Hidden attribute</p>
+		<p id="p4" style="visibility: hidden"><span id="s3" style="visibility: inherit">A</span>
+			<span id="s4" style="display: inherit">B</span></p>
+		<script id="script1">var x = 1;</script>
+	</body></html>`
+
+	doc, err := html.Parse(strings.NewReader(htmlSource))
+	if err != nil {
+		t.Fatalf("ComputedStyle(), failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		id   string
+		want dom.ComputedValues
+	}{
+		{"p1", dom.ComputedValues{"block", "hidden", "normal", "normal"}},
+		{"s1", dom.ComputedValues{"inline", "hidden", "normal", "normal"}},
+		{"p2", dom.ComputedValues{"block", "visible", "normal", "normal"}},
+		{"d1", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+		{"d2", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+		{"d3", dom.ComputedValues{"block", "visible", "normal", "normal"}},
+		{"d4", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+		{"d5", dom.ComputedValues{"block", "visible", "normal", "normal"}},
+		{"pre1", dom.ComputedValues{"block", "visible", "pre", "normal"}},
+		{"code1", dom.ComputedValues{"inline", "visible", "pre-wrap", "normal"}},
+		{"s2", dom.ComputedValues{"inline", "visible", "pre-wrap", "normal"}},
+		{"q1", dom.ComputedValues{"inline", "visible", "normal", `"\201C"`}},
+		{"p3", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+		{"s3", dom.ComputedValues{"inline", "hidden", "normal", "normal"}},
+		{"s4", dom.ComputedValues{"block", "hidden", "normal", "normal"}},
+		{"script1", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+	}
+
+	cascade := dom.NewCascade(doc, dom.CascadeOptions{})
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			node := dom.GetElementByID(doc, tt.id)
+			if got := cascade.ComputedStyle(node); got != tt.want {
+				t.Errorf("ComputedStyle() = %+v, want %+v", got, tt.want)
+			}
+
+			if got := dom.ComputedStyle(node); got != tt.want {
+				t.Errorf("ComputedStyle() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Text node inherits from its parent
+	text := dom.GetElementByID(doc, "p1").FirstChild
+	want := dom.ComputedValues{"inline", "hidden", "normal", "normal"}
+	if got := cascade.ComputedStyle(text); got != want {
+		t.Errorf("ComputedStyle() = %+v, want %+v", got, want)
+	}
+
+	if got := want.GetPropertyValue("Visibility"); got != "hidden" {
+		t.Errorf("GetPropertyValue() = %v, want %v", got, "hidden")
+	}
+}
+
+func TestCascadeExternalStylesheet(t *testing.T) {
+	htmlSource := `<html><head>
+		<link rel="stylesheet" href="main.css">
+		<link rel="alternate stylesheet" href="alt.css">
+		<link rel="stylesheet" href="missing.css">
+		<style>@import url("imported.css");</style>
+	</head><body>
+		<p id="p1" class="a">A</p>
+		<p id="p2" class="b">B</p>
+		<p id="p3" class="c">C</p>
+	</body></html>`
+
+	stylesheets := map[string]string{
+		"main.css":     ".a { display: none }",
+		"alt.css":      ".b { display: none }",
+		"imported.css": `@import "nested.css"; .c { visibility: hidden }`,
+		"nested.css":   ".b { white-space: pre }",
+	}
+
+	doc, err := html.Parse(strings.NewReader(htmlSource))
+	if err != nil {
+		t.Fatalf("NewCascade(), failed to parse: %v", err)
+	}
+
+	cascade := dom.NewCascade(doc, dom.CascadeOptions{
+		ResolveStylesheet: func(href string) (string, bool) {
+			css, ok := stylesheets[href]
+			return css, ok
+		},
+	})
+
+	tests := []struct {
+		id   string
+		want dom.ComputedValues
+	}{
+		{"p1", dom.ComputedValues{"none", "visible", "normal", "normal"}},
+		{"p2", dom.ComputedValues{"block", "visible", "pre", "normal"}},
+		{"p3", dom.ComputedValues{"block", "hidden", "normal", "normal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			node := dom.GetElementByID(doc, tt.id)
+			if got := cascade.ComputedStyle(node); got != tt.want {
+				t.Errorf("ComputedStyle() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
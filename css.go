@@ -0,0 +1,181 @@
+package dom
+
+import (
+	"regexp"
+	"strings"
+)
+
+var rxImportant = regexp.MustCompile(`(?i)!\s*important\s*$`)
+
+// cssDeclaration is a single property declaration in CSS,
+// e.g. `display: none !important`.
+type cssDeclaration struct {
+	name      string
+	value     string
+	important bool
+}
+
+// parseCSSDeclarations parses a list of CSS declarations, e.g. the content of
+// style attribute or the block of a style rule. Invalid declarations are
+// skipped. If a property is declared more than once, the last one is used
+// unless the previous one is important and the last one is not.
+func parseCSSDeclarations(text string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, part := range splitCSS(stripCSSComments(text), ';') {
+		colonIdx := indexCSS(part, ':')
+		if colonIdx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(part[:colonIdx])
+		if name == "" || strings.IndexFunc(name, isASCIIWhitespace) >= 0 {
+			continue
+		}
+
+		name = normalizeCSSPropertyName(name)
+		value := strings.TrimSpace(part[colonIdx+1:])
+		important := false
+		if loc := rxImportant.FindStringIndex(value); loc != nil {
+			value = strings.TrimSpace(value[:loc[0]])
+			important = true
+		}
+
+		if value == "" && !strings.HasPrefix(name, "--") {
+			continue
+		}
+
+		declarations = setCSSDeclaration(declarations, cssDeclaration{
+			name:      name,
+			value:     value,
+			important: important,
+		}, false)
+	}
+
+	return declarations
+}
+
+// normalizeCSSPropertyName converts the property name into lowercase, since
+// it's case-insensitive. The exception is custom property, e.g. `--main-color`.
+func normalizeCSSPropertyName(name string) string {
+	if strings.HasPrefix(name, "--") {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// setCSSDeclaration puts the declaration into the list. If the property
+// already exists, the old one is removed and the new one is appended to the
+// end of list. However, if force is false, important declaration won't be
+// replaced by the normal one.
+func setCSSDeclaration(declarations []cssDeclaration, decl cssDeclaration, force bool) []cssDeclaration {
+	for i, existing := range declarations {
+		if existing.name != decl.name {
+			continue
+		}
+
+		if existing.important && !decl.important && !force {
+			return declarations
+		}
+
+		declarations = append(declarations[:i], declarations[i+1:]...)
+		break
+	}
+
+	return append(declarations, decl)
+}
+
+// serializeCSSDeclarations converts the list of declarations back into text.
+func serializeCSSDeclarations(declarations []cssDeclaration) string {
+	parts := make([]string, len(declarations))
+	for i, decl := range declarations {
+		parts[i] = decl.name + ": " + decl.value
+		if decl.important {
+			parts[i] += " !important"
+		}
+		parts[i] += ";"
+	}
+	return strings.Join(parts, " ")
+}
+
+// stripCSSComments removes all comments from the CSS text. Each comment is
+// replaced by a single space, since in CSS comment separates tokens.
+func stripCSSComments(text string) string {
+	if !strings.Contains(text, "/*") {
+		return text
+	}
+
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		switch {
+		case char == '\\' && i+1 < len(text):
+			sb.WriteByte(char)
+			sb.WriteByte(text[i+1])
+			i++
+			continue
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '/' && i+1 < len(text) && text[i+1] == '*':
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			sb.WriteByte(' ')
+			i += end + 3
+			continue
+		}
+		sb.WriteByte(char)
+	}
+
+	return sb.String()
+}
+
+// splitCSS splits the CSS text by the separator that is not
+// nested inside string, parentheses, brackets or braces.
+func splitCSS(text string, sep byte) []string {
+	var parts []string
+	for {
+		idx := indexCSS(text, sep)
+		if idx < 0 {
+			return append(parts, text)
+		}
+
+		parts = append(parts, text[:idx])
+		text = text[idx+1:]
+	}
+}
+
+// indexCSS returns the index of the first char that is not nested inside
+// string, parentheses, brackets or braces, or -1 if it doesn't exist.
+// The text must be already stripped from comments.
+func indexCSS(text string, char byte) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == char && depth == 0:
+			return i
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return -1
+}
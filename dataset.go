@@ -0,0 +1,137 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DataMap is a map-like view of the custom data attributes (data-*) of an
+// element, similar with DOMStringMap that returned by `dataset` in JS. It's a
+// live view which works directly on the attributes of its node. The names are
+// converted between camelCase property and kebab-case attribute following the
+// rules in HTML specification, e.g. `data-lazy-src` is accessed as `lazySrc`.
+type DataMap struct {
+	node *html.Node
+}
+
+// Dataset returns a DataMap for the custom data attributes of the node.
+func Dataset(node *html.Node) *DataMap {
+	return &DataMap{node: node}
+}
+
+// Get returns the value of the data attribute with the specified name,
+// and a Boolean value indicating whether the attribute exists or not.
+func (dm *DataMap) Get(name string) (string, bool) {
+	for _, attr := range dm.node.Attr {
+		if propName, ok := dataPropertyName(attr); ok && propName == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the data attribute with the specified name. If the
+// name is invalid, i.e. contains hyphen followed by lowercase letter, it's
+// ignored since it can't be converted back from the attribute name.
+func (dm *DataMap) Set(name string, value string) {
+	attrName, ok := dataAttributeName(name)
+	if !ok {
+		return
+	}
+
+	SetAttribute(dm.node, attrName, value)
+}
+
+// Delete removes the data attribute with the specified name.
+func (dm *DataMap) Delete(name string) {
+	attrName, ok := dataAttributeName(name)
+	if !ok {
+		return
+	}
+
+	RemoveAttribute(dm.node, attrName)
+}
+
+// Keys returns the names of all data attributes, in the same order
+// as their position in the element.
+func (dm *DataMap) Keys() []string {
+	var keys []string
+	for _, attr := range dm.node.Attr {
+		if propName, ok := dataPropertyName(attr); ok {
+			keys = append(keys, propName)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for each data attribute in order. If fn returns
+// false, the iteration will be stopped.
+func (dm *DataMap) Range(fn func(name string, value string) bool) {
+	for _, attr := range dm.node.Attr {
+		if propName, ok := dataPropertyName(attr); ok {
+			if !fn(propName, attr.Val) {
+				return
+			}
+		}
+	}
+}
+
+// dataPropertyName converts the name of data attribute into its camelCase
+// property name. Returns false if the attribute is not a data attribute.
+func dataPropertyName(attr html.Attribute) (string, bool) {
+	if attr.Namespace != "" || !strings.HasPrefix(attr.Key, "data-") {
+		return "", false
+	}
+
+	// Attribute with uppercase letter is not exposed in dataset
+	name := attr.Key[len("data-"):]
+	if strings.IndexFunc(name, isASCIIUpper) >= 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '-' && i+1 < len(name) && isASCIILower(rune(name[i+1])) {
+			sb.WriteByte(name[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		sb.WriteByte(name[i])
+	}
+
+	return sb.String(), true
+}
+
+// dataAttributeName converts the camelCase property name into the name of data
+// attribute. Returns false if the name contains hyphen followed by lowercase
+// letter, which is not allowed in the property name.
+func dataAttributeName(name string) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString("data-")
+
+	for i := 0; i < len(name); i++ {
+		char := name[i]
+		if char == '-' && i+1 < len(name) && isASCIILower(rune(name[i+1])) {
+			return "", false
+		}
+
+		if isASCIIUpper(rune(char)) {
+			sb.WriteByte('-')
+			sb.WriteByte(char - 'A' + 'a')
+			continue
+		}
+
+		sb.WriteByte(char)
+	}
+
+	return sb.String(), true
+}
+
+func isASCIIUpper(r rune) bool {
+	return r >= 'A' && r <= 'Z'
+}
+
+func isASCIILower(r rune) bool {
+	return r >= 'a' && r <= 'z'
+}
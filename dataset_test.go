@@ -0,0 +1,111 @@
+package dom_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestDatasetGet(t *testing.T) {
+	htmlSource := `<img data-src="a.jpg" data-lazy-srcset="a.jpg 1x" data-original="b.jpg"` +
+		` data-foo--bar="double" data--baz="leading" data-x-1="digit" data-="empty" src="c.jpg">`
+
+	doc, err := parseHTMLSource(htmlSource)
+	if err != nil {
+		t.Errorf("Dataset(), failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		wantValue string
+		wantExist bool
+	}{
+		{"src", "a.jpg", true},
+		{"lazySrcset", "a.jpg 1x", true},
+		{"original", "b.jpg", true},
+		{"foo-Bar", "double", true},
+		{"Baz", "leading", true},
+		{"x-1", "digit", true},
+		{"", "empty", true},
+		{"lazy-srcset", "", false},
+		{"unknown", "", false},
+	}
+
+	dataset := dom.Dataset(doc.FirstChild)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, exist := dataset.Get(tt.name)
+			if value != tt.wantValue || exist != tt.wantExist {
+				t.Errorf("Get() = (%v, %v), want (%v, %v)", value, exist, tt.wantValue, tt.wantExist)
+			}
+		})
+	}
+
+	wantKeys := []string{"src", "lazySrcset", "original", "foo-Bar", "Baz", "x-1", ""}
+	if got := dataset.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+}
+
+func TestDatasetModify(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		modify     func(dm *dom.DataMap)
+		want       string
+	}{{
+		name:       "set new attribute",
+		htmlSource: `<img src="a.jpg">`,
+		modify:     func(dm *dom.DataMap) { dm.Set("lazySrc", "b.jpg") },
+		want:       `<img src="a.jpg" data-lazy-src="b.jpg"/>`,
+	}, {
+		name:       "replace existing attribute",
+		htmlSource: `<img data-lazy-src="a.jpg">`,
+		modify:     func(dm *dom.DataMap) { dm.Set("lazySrc", "b.jpg") },
+		want:       `<img data-lazy-src="b.jpg"/>`,
+	}, {
+		name:       "set invalid name is ignored",
+		htmlSource: `<img>`,
+		modify:     func(dm *dom.DataMap) { dm.Set("lazy-src", "b.jpg") },
+		want:       `<img/>`,
+	}, {
+		name:       "delete attribute",
+		htmlSource: `<img data-lazy-src="a.jpg" data-src="b.jpg">`,
+		modify:     func(dm *dom.DataMap) { dm.Delete("lazySrc") },
+		want:       `<img data-src="b.jpg"/>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("Dataset(), failed to parse: %v", err)
+			}
+
+			node := doc.FirstChild
+			tt.modify(dom.Dataset(node))
+			if got := dom.OuterHTML(node); got != tt.want {
+				t.Errorf("Dataset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatasetRange(t *testing.T) {
+	doc, err := parseHTMLSource(`<img data-a="1" src="x.jpg" data-b-c="2" data-d="3">`)
+	if err != nil {
+		t.Errorf("Dataset(), failed to parse: %v", err)
+	}
+
+	var pairs []string
+	dom.Dataset(doc.FirstChild).Range(func(name, value string) bool {
+		pairs = append(pairs, name+"="+value)
+		return name != "bC"
+	})
+
+	want := []string{"a=1", "bC=2"}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("Range() = %v, want %v", pairs, want)
+	}
+}
@@ -0,0 +1,563 @@
+package dom
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// EditType is the type of an edit operation that produced by Diff.
+type EditType int
+
+const (
+	// EditInsert inserts Node at Path, i.e. before the child that
+	// currently at that index, or at the end of the parent.
+	EditInsert EditType = iota
+
+	// EditDelete removes the node at Path.
+	EditDelete
+
+	// EditMove moves the node at Path to To. Both of them are in the same
+	// parent, and the index in To is counted after the node is removed.
+	EditMove
+
+	// EditReplace replaces the node at Path with Node. It's only used when
+	// the roots of the compared trees are not the same kind of node.
+	EditReplace
+
+	// EditText changes the data of text, comment or doctype at Path
+	// from OldValue into NewValue.
+	EditText
+
+	// EditSetAttribute sets the attribute of node at Path into NewValue.
+	// If the attribute doesn't exist before, OldValue is empty.
+	EditSetAttribute
+
+	// EditRemoveAttribute removes the attribute of node at Path.
+	EditRemoveAttribute
+)
+
+var editTypeNames = []string{
+	EditInsert:          "insert",
+	EditDelete:          "delete",
+	EditMove:            "move",
+	EditReplace:         "replace",
+	EditText:            "text",
+	EditSetAttribute:    "set-attribute",
+	EditRemoveAttribute: "remove-attribute",
+}
+
+// String returns the name of the edit type, e.g. "insert".
+func (t EditType) String() string {
+	if t < 0 || int(t) >= len(editTypeNames) {
+		return "EditType(" + strconv.Itoa(int(t)) + ")"
+	}
+	return editTypeNames[t]
+}
+
+// Edit is an edit operation that transforms a tree into another tree.
+type Edit struct {
+	Type EditType
+
+	// Path is the path of the edited node from the root, i.e. the index of
+	// each node among the child nodes of its parent. The path is valid for
+	// the tree where all of the previous edits have been applied.
+	Path []int
+
+	// To is the destination path of EditMove.
+	To []int
+
+	// Node is the node from the new tree for EditInsert and EditReplace, or
+	// the edited node from the old tree for the other edits.
+	Node *html.Node
+
+	// Namespace and Key is the name of attribute
+	// for EditSetAttribute and EditRemoveAttribute.
+	Namespace string
+	Key       string
+
+	// OldValue and NewValue is the data of node for EditText,
+	// or the value of attribute for the attribute edits.
+	OldValue string
+	NewValue string
+}
+
+// Diff compares the trees in a and b, and returns the list of edit operations
+// that transform a into b. The edits are ordered, so they must be applied one
+// by one. To find the edits, each child node in b is matched with a child of
+// the same kind in a, first by comparing their whole subtree, then by their ID,
+// then by their order. The matched nodes that are reordered become EditMove,
+// so reordering siblings doesn't remove and re-insert their whole subtree.
+// The edits can be applied to a using ApplyPatch.
+func Diff(a, b *html.Node) []Edit {
+	if a == nil || b == nil {
+		return nil
+	}
+
+	d := differ{hashes: make(map[*html.Node]uint64)}
+	if !isComparableNode(a, b) {
+		return []Edit{{Type: EditReplace, Path: []int{}, Node: b}}
+	}
+
+	d.diffNode(a, b, []int{})
+	return d.edits
+}
+
+// RenderDiffReport renders the edits that produced by Diff as an HTML document,
+// which lists each edit with its path and highlights the removed and inserted
+// content.
+func RenderDiffReport(w io.Writer, edits []Edit) error {
+	rw := &renderWriter{w: w}
+	rw.writeString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Diff report</title>\n" +
+		"<style>\n" +
+		"body{font-family:sans-serif}\n" +
+		".edit{margin:.5em 0}\n" +
+		".type{display:inline-block;min-width:9em;font-weight:bold}\n" +
+		"del{background:#fdd;color:#900}\n" +
+		"ins{background:#dfd;color:#060;text-decoration:none}\n" +
+		"pre{margin:.25em 0;white-space:pre-wrap}\n" +
+		"</style></head>\n<body>\n")
+
+	if len(edits) == 0 {
+		rw.writeString("<p>No changes.</p>\n</body></html>\n")
+		return rw.err
+	}
+
+	rw.writeString("<ol>\n")
+	for _, edit := range edits {
+		rw.writeString(`<li class="edit edit-` + edit.Type.String() + `">`)
+		rw.writeString(`<span class="type">` + edit.Type.String() + `</span> `)
+		rw.writeString(`<code class="path">` + formatPath(edit.Path) + `</code> `)
+		if edit.Node != nil {
+			rw.writeString(`<code class="node">` + html.EscapeString(nodeLabel(edit.Node)) + `</code>`)
+		}
+
+		switch edit.Type {
+		case EditInsert:
+			rw.writeString("<ins><pre>" + html.EscapeString(OuterHTML(edit.Node)) + "</pre></ins>")
+		case EditDelete:
+			rw.writeString("<del><pre>" + html.EscapeString(OuterHTML(edit.Node)) + "</pre></del>")
+		case EditReplace:
+			rw.writeString("<ins><pre>" + html.EscapeString(OuterHTML(edit.Node)) + "</pre></ins>")
+		case EditMove:
+			rw.writeString(` to <code class="path">` + formatPath(edit.To) + `</code>`)
+		case EditText:
+			rw.writeString("<del><pre>" + html.EscapeString(edit.OldValue) + "</pre></del>")
+			rw.writeString("<ins><pre>" + html.EscapeString(edit.NewValue) + "</pre></ins>")
+		case EditSetAttribute, EditRemoveAttribute:
+			rw.writeString(` <code class="attr">` + html.EscapeString(editAttributeName(edit)) + `</code> `)
+			if edit.OldValue != "" || edit.Type == EditRemoveAttribute {
+				rw.writeString("<del>" + html.EscapeString(strconv.Quote(edit.OldValue)) + "</del> ")
+			}
+			if edit.Type == EditSetAttribute {
+				rw.writeString("<ins>" + html.EscapeString(strconv.Quote(edit.NewValue)) + "</ins>")
+			}
+		}
+		rw.writeString("</li>\n")
+	}
+
+	rw.writeString("</ol>\n</body></html>\n")
+	return rw.err
+}
+
+type differ struct {
+	edits  []Edit
+	hashes map[*html.Node]uint64
+}
+
+func (d *differ) diffNode(a, b *html.Node, path []int) {
+	if d.identical(a, b) {
+		return
+	}
+
+	if a.Type != html.ElementNode && a.Type != html.DocumentNode && a.Data != b.Data {
+		d.edits = append(d.edits, Edit{
+			Type:     EditText,
+			Path:     path,
+			Node:     a,
+			OldValue: a.Data,
+			NewValue: b.Data,
+		})
+	}
+
+	d.diffAttributes(a, b, path)
+	d.diffChildren(a, b, path)
+}
+
+func (d *differ) diffAttributes(a, b *html.Node, path []int) {
+	newAttrs := make(map[string]string, len(b.Attr))
+	for _, attr := range b.Attr {
+		newAttrs[attr.Namespace+" "+attr.Key] = attr.Val
+	}
+
+	oldAttrs := make(map[string]string, len(a.Attr))
+	for _, attr := range a.Attr {
+		name := attr.Namespace + " " + attr.Key
+		oldAttrs[name] = attr.Val
+		if _, exist := newAttrs[name]; !exist {
+			d.edits = append(d.edits, Edit{
+				Type:      EditRemoveAttribute,
+				Path:      path,
+				Node:      a,
+				Namespace: attr.Namespace,
+				Key:       attr.Key,
+				OldValue:  attr.Val,
+			})
+		}
+	}
+
+	for _, attr := range b.Attr {
+		oldValue, exist := oldAttrs[attr.Namespace+" "+attr.Key]
+		if !exist || oldValue != attr.Val {
+			d.edits = append(d.edits, Edit{
+				Type:      EditSetAttribute,
+				Path:      path,
+				Node:      a,
+				Namespace: attr.Namespace,
+				Key:       attr.Key,
+				OldValue:  oldValue,
+				NewValue:  attr.Val,
+			})
+		}
+	}
+}
+
+func (d *differ) diffChildren(a, b *html.Node, path []int) {
+	oldChildren, newChildren := ChildNodes(a), ChildNodes(b)
+	matches := d.matchChildren(oldChildren, newChildren)
+
+	// Remove the children that don't exist anymore. It's done from the
+	// last child, so the index of the other children is not changed.
+	matched := make([]bool, len(oldChildren))
+	for _, idx := range matches {
+		if idx >= 0 {
+			matched[idx] = true
+		}
+	}
+
+	var current []*html.Node
+	for i := len(oldChildren) - 1; i >= 0; i-- {
+		if !matched[i] {
+			d.edits = append(d.edits, Edit{Type: EditDelete, Path: childPath(path, i), Node: oldChildren[i]})
+		}
+	}
+
+	for i, child := range oldChildren {
+		if matched[i] {
+			current = append(current, child)
+		}
+	}
+
+	// Move the matched children that are not in the longest sequence that
+	// keeps their order. Each of them is put right after its nearest matched
+	// previous sibling in b, so at the end all of them are in the right order.
+	stable := longestIncreasingSubsequence(matches)
+	var previous *html.Node
+	for j, idx := range matches {
+		if idx < 0 {
+			continue
+		}
+
+		child := oldChildren[idx]
+		if !stable[j] {
+			from := indexOfNode(current, child)
+			current = append(current[:from], current[from+1:]...)
+
+			to := 0
+			if previous != nil {
+				to = indexOfNode(current, previous) + 1
+			}
+			current = append(current[:to], append([]*html.Node{child}, current[to:]...)...)
+
+			if from != to {
+				d.edits = append(d.edits, Edit{
+					Type: EditMove,
+					Path: childPath(path, from),
+					To:   childPath(path, to),
+					Node: child,
+				})
+			}
+		}
+		previous = child
+	}
+
+	// Insert the new children, which at this point can be
+	// inserted right at their index in the new tree.
+	for j, idx := range matches {
+		if idx < 0 {
+			d.edits = append(d.edits, Edit{Type: EditInsert, Path: childPath(path, j), Node: newChildren[j]})
+		}
+	}
+
+	for j, idx := range matches {
+		if idx >= 0 {
+			d.diffNode(oldChildren[idx], newChildren[j], childPath(path, j))
+		}
+	}
+}
+
+// matchChildren returns the index of the old child that
+// matched with each new child, or -1 if it's a new node.
+func (d *differ) matchChildren(oldChildren, newChildren []*html.Node) []int {
+	matches := make([]int, len(newChildren))
+	used := make([]bool, len(oldChildren))
+	for j := range matches {
+		matches[j] = -1
+	}
+
+	// First, match the identical subtrees
+	identicals := make(map[uint64][]int)
+	for i, child := range oldChildren {
+		hash := d.hash(child)
+		identicals[hash] = append(identicals[hash], i)
+	}
+
+	for j, child := range newChildren {
+		hash := d.hash(child)
+		candidates := identicals[hash]
+		for k, i := range candidates {
+			if d.identical(oldChildren[i], child) {
+				matches[j], used[i] = i, true
+				identicals[hash] = append(candidates[:k:k], candidates[k+1:]...)
+				break
+			}
+		}
+	}
+
+	// Next, match the elements that have the same ID
+	ids := make(map[string]int)
+	for i, child := range oldChildren {
+		if id := elementKey(child); id != "" && !used[i] {
+			if _, exist := ids[id]; !exist {
+				ids[id] = i
+			}
+		}
+	}
+
+	for j, child := range newChildren {
+		if matches[j] >= 0 {
+			continue
+		}
+
+		if i, exist := ids[elementKey(child)]; exist && !used[i] {
+			matches[j], used[i] = i, true
+		}
+	}
+
+	// Finally, match the remaining nodes of the same kind by their order
+	for j, child := range newChildren {
+		if matches[j] >= 0 {
+			continue
+		}
+
+		for i, oldChild := range oldChildren {
+			if !used[i] && isComparableNode(oldChild, child) {
+				matches[j], used[i] = i, true
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// identical check whether the nodes and their descendants are the same. Since
+// different nodes might have the same hash, the hash is only used to quickly
+// tell the different nodes, and the same hash is confirmed by comparing them.
+func (d *differ) identical(a, b *html.Node) bool {
+	if d.hash(a) != d.hash(b) {
+		return false
+	}
+
+	if a.Type != b.Type || a.Namespace != b.Namespace || a.Data != b.Data || !equalAttributes(a.Attr, b.Attr) {
+		return false
+	}
+
+	childA, childB := a.FirstChild, b.FirstChild
+	for ; childA != nil && childB != nil; childA, childB = childA.NextSibling, childB.NextSibling {
+		if !d.identical(childA, childB) {
+			return false
+		}
+	}
+	return childA == nil && childB == nil
+}
+
+// hash returns the hash of the node and its descendants.
+func (d *differ) hash(node *html.Node) uint64 {
+	if hash, exist := d.hashes[node]; exist {
+		return hash
+	}
+
+	h := fnv.New64a()
+	writeField := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	writeField(strconv.Itoa(int(node.Type)))
+	writeField(node.Namespace)
+	writeField(node.Data)
+	for _, attr := range node.Attr {
+		writeField(attr.Namespace)
+		writeField(attr.Key)
+		writeField(attr.Val)
+	}
+
+	var buffer [8]byte
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		binary.LittleEndian.PutUint64(buffer[:], d.hash(child))
+		h.Write(buffer[:])
+	}
+
+	hash := h.Sum64()
+	d.hashes[node] = hash
+	return hash
+}
+
+// isComparableNode check whether node a can be transformed into b
+// without replacing it, i.e. both of them are the same kind of node.
+func isComparableNode(a, b *html.Node) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	switch a.Type {
+	case html.ElementNode:
+		return a.Data == b.Data && a.Namespace == b.Namespace
+	case html.DocumentNode:
+		return IsDocumentFragment(a) == IsDocumentFragment(b)
+	default:
+		return true
+	}
+}
+
+// elementKey returns the key to match the element by its ID,
+// or empty string if it's not an element or doesn't have ID.
+func elementKey(node *html.Node) string {
+	if node.Type != html.ElementNode {
+		return ""
+	}
+
+	id := GetAttribute(node, "id")
+	if id == "" {
+		return ""
+	}
+	return node.Namespace + " " + node.Data + " " + id
+}
+
+// longestIncreasingSubsequence marks the matches that are in the longest
+// subsequence whose old index is increasing, i.e. the matched nodes
+// that don't need to be moved. The unmatched (negative) index is skipped.
+func longestIncreasingSubsequence(matches []int) []bool {
+	var tails []int // index in matches of the smallest tail for each length
+	previous := make([]int, len(matches))
+	for j, idx := range matches {
+		if idx < 0 {
+			continue
+		}
+
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if matches[tails[mid]] < idx {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+
+		previous[j] = -1
+		if lo > 0 {
+			previous[j] = tails[lo-1]
+		}
+
+		if lo == len(tails) {
+			tails = append(tails, j)
+		} else {
+			tails[lo] = j
+		}
+	}
+
+	stable := make([]bool, len(matches))
+	if len(tails) > 0 {
+		for j := tails[len(tails)-1]; j >= 0; j = previous[j] {
+			stable[j] = true
+		}
+	}
+	return stable
+}
+
+func indexOfNode(nodes []*html.Node, node *html.Node) int {
+	for i, n := range nodes {
+		if n == node {
+			return i
+		}
+	}
+	return -1
+}
+
+func childPath(path []int, idx int) []int {
+	childPath := make([]int, len(path)+1)
+	copy(childPath, path)
+	childPath[len(path)] = idx
+	return childPath
+}
+
+// formatPath formats the path as slash-separated index, e.g. "/0/1/3".
+func formatPath(path []int) string {
+	if len(path) == 0 {
+		return "/"
+	}
+
+	var sb strings.Builder
+	for _, idx := range path {
+		sb.WriteString("/")
+		sb.WriteString(strconv.Itoa(idx))
+	}
+	return sb.String()
+}
+
+// nodeLabel returns the short description of node that used in the diff report,
+// e.g. "div#main.content" for element, or "#text" for text node.
+func nodeLabel(node *html.Node) string {
+	switch node.Type {
+	case html.ElementNode:
+		label := node.Data
+		if node.Namespace != "" {
+			label = node.Namespace + ":" + label
+		}
+
+		if id := GetAttribute(node, "id"); id != "" {
+			label += "#" + id
+		}
+
+		for _, class := range strings.Fields(GetAttribute(node, "class")) {
+			label += "." + class
+		}
+		return label
+	case html.TextNode:
+		return "#text"
+	case html.CommentNode:
+		return "#comment"
+	case html.DoctypeNode:
+		return "#doctype"
+	case html.DocumentNode:
+		if IsDocumentFragment(node) {
+			return documentFragmentData
+		}
+		return "#document"
+	default:
+		return "#error"
+	}
+}
+
+func editAttributeName(edit Edit) string {
+	if edit.Namespace == "" {
+		return edit.Key
+	}
+	return edit.Namespace + ":" + edit.Key
+}
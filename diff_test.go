@@ -0,0 +1,163 @@
+package dom_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func describeEdits(edits []dom.Edit) []string {
+	var descriptions []string
+	for _, edit := range edits {
+		path := fmt.Sprint(edit.Path)
+		switch edit.Type {
+		case dom.EditInsert, dom.EditDelete, dom.EditReplace:
+			descriptions = append(descriptions, fmt.Sprintf("%s %s %s", edit.Type, path, dom.OuterHTML(edit.Node)))
+		case dom.EditMove:
+			descriptions = append(descriptions, fmt.Sprintf("move %s %v", path, edit.To))
+		case dom.EditText:
+			descriptions = append(descriptions, fmt.Sprintf("text %s %q %q", path, edit.OldValue, edit.NewValue))
+		case dom.EditSetAttribute:
+			descriptions = append(descriptions, fmt.Sprintf("set-attribute %s %s=%q", path, edit.Key, edit.NewValue))
+		case dom.EditRemoveAttribute:
+			descriptions = append(descriptions, fmt.Sprintf("remove-attribute %s %s", path, edit.Key))
+		}
+	}
+	return descriptions
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{{
+		name: "identical",
+		a:    `<p class="a">x</p><p>y</p>`,
+		b:    `<p class="a">x</p><p>y</p>`,
+		want: nil,
+	}, {
+		name: "text change",
+		a:    `<p>hello</p>`,
+		b:    `<p>world</p>`,
+		want: []string{`text [0 1 0 0] "hello" "world"`},
+	}, {
+		name: "attribute change",
+		a:    `<a href="/a" title="x">link</a>`,
+		b:    `<a href="/b" rel="next">link</a>`,
+		want: []string{
+			`remove-attribute [0 1 0] title`,
+			`set-attribute [0 1 0] href="/b"`,
+			`set-attribute [0 1 0] rel="next"`,
+		},
+	}, {
+		name: "insert",
+		a:    `<p>a</p><p>c</p>`,
+		b:    `<p>a</p><p>b</p><p>c</p>`,
+		want: []string{`insert [0 1 1] <p>b</p>`},
+	}, {
+		name: "delete",
+		a:    `<p>a</p><p>b</p><p>c</p><p>d</p>`,
+		b:    `<p>a</p><p>c</p>`,
+		want: []string{`delete [0 1 3] <p>d</p>`, `delete [0 1 1] <p>b</p>`},
+	}, {
+		name: "move first to last",
+		a:    `<p>a</p><p>b</p><p>c</p><p>d</p>`,
+		b:    `<p>b</p><p>c</p><p>d</p><p>a</p>`,
+		want: []string{`move [0 1 0] [0 1 3]`},
+	}, {
+		name: "swap",
+		a:    `<ul><li>a</li><li>b</li><li>c</li></ul>`,
+		b:    `<ul><li>c</li><li>b</li><li>a</li></ul>`,
+		want: []string{`move [0 1 0 2] [0 1 0 0]`, `move [0 1 0 2] [0 1 0 1]`},
+	}, {
+		name: "reordered and changed",
+		a:    `<div id="x">one</div><div id="y">two</div>`,
+		b:    `<div id="y">two!</div><div id="x">one</div>`,
+		want: []string{`move [0 1 1] [0 1 0]`, `text [0 1 0 0] "two" "two!"`},
+	}, {
+		name: "different element",
+		a:    `<p>a</p>`,
+		b:    `<div>a</div>`,
+		want: []string{`delete [0 1 0] <p>a</p>`, `insert [0 1 0] <div>a</div>`},
+	}, {
+		name: "comment",
+		a:    `<!-- a --><p>x</p>`,
+		b:    `<!-- b --><p>x</p>`,
+		want: []string{`text [0] " a " " b "`},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := dom.FastParse(strings.NewReader(tt.a))
+			if err != nil {
+				t.Fatalf("Diff(), failed to parse: %v", err)
+			}
+
+			b, err := dom.FastParse(strings.NewReader(tt.b))
+			if err != nil {
+				t.Fatalf("Diff(), failed to parse: %v", err)
+			}
+
+			got := describeEdits(dom.Diff(a, b))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Diff() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffReplaceRoot(t *testing.T) {
+	a, b := dom.CreateElement("p"), dom.CreateElement("div")
+	got := describeEdits(dom.Diff(a, b))
+	want := []string{`replace [] <div></div>`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestRenderDiffReport(t *testing.T) {
+	a, err := dom.FastParse(strings.NewReader(`<p class="x">old &amp; text</p><p>gone</p>`))
+	if err != nil {
+		t.Fatalf("RenderDiffReport(), failed to parse: %v", err)
+	}
+
+	b, err := dom.FastParse(strings.NewReader(`<p class="y">new text</p><div>added</div>`))
+	if err != nil {
+		t.Fatalf("RenderDiffReport(), failed to parse: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := dom.RenderDiffReport(&sb, dom.Diff(a, b)); err != nil {
+		t.Fatalf("RenderDiffReport() error = %v", err)
+	}
+
+	report, err := html.Parse(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("RenderDiffReport(), failed to parse report: %v", err)
+	}
+
+	var texts []string
+	for _, node := range dom.QuerySelectorAll(report, "del, ins") {
+		texts = append(texts, dom.TagName(node)+":"+dom.TextContent(node))
+	}
+
+	got := strings.Join(texts, " ")
+	want := `del:<p>gone</p> ins:<div>added</div> del:"x" ins:"y" del:old & text ins:new text`
+	if got != want {
+		t.Errorf("RenderDiffReport() = %q, want %q", got, want)
+	}
+
+	sb.Reset()
+	if err := dom.RenderDiffReport(&sb, nil); err != nil {
+		t.Fatalf("RenderDiffReport() error = %v", err)
+	}
+
+	if !strings.Contains(sb.String(), "No changes.") {
+		t.Errorf("RenderDiffReport() without edits = %q", sb.String())
+	}
+}
@@ -0,0 +1,207 @@
+package dom
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document is a wrapper around the root node of an HTML document. It gives
+// access to the document-level properties that in JS are only available from
+// the `document` object, e.g. head, body and title. The wrapped node is still
+// a plain html.Node, so all free functions in this package keep working on it.
+type Document struct {
+	// Node is the root node of the document.
+	Node *html.Node
+
+	// URL is the address of the document. It's used as fallback for
+	// BaseURI and to resolve the relative URL in <base> element.
+	URL string
+
+	charset string
+	source  *sourceMap
+}
+
+// NewDocument creates a new Document which wraps the specified root node.
+// Since the character set is unknown, it's assumed to be UTF-8.
+func NewDocument(root *html.Node) *Document {
+	return &Document{
+		Node:    root,
+		charset: "utf-8",
+	}
+}
+
+// DocumentElement returns the root element of the document,
+// which is the <html> element.
+func (d *Document) DocumentElement() *html.Node {
+	for child := d.Node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// Head returns the <head> element of the document, or nil
+// if there are none.
+func (d *Document) Head() *html.Node {
+	root := d.DocumentElement()
+	if root == nil {
+		return nil
+	}
+
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "head" {
+			return child
+		}
+	}
+	return nil
+}
+
+// Body returns the <body> or <frameset> element of the document,
+// or nil if there are none.
+func (d *Document) Body() *html.Node {
+	root := d.DocumentElement()
+	if root == nil {
+		return nil
+	}
+
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.Data == "body" || child.Data == "frameset") {
+			return child
+		}
+	}
+	return nil
+}
+
+// Title returns the title of the document, which is the text content of the
+// first <title> element with its whitespace stripped and collapsed.
+func (d *Document) Title() string {
+	title := d.titleElement()
+	if title == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(TextContent(title)), " ")
+}
+
+// SetTitle sets the title of the document. If the <title> element doesn't
+// exist yet, it will be created inside the <head> element.
+func (d *Document) SetTitle(title string) {
+	titleElement := d.titleElement()
+	if titleElement == nil {
+		head := d.Head()
+		if head == nil {
+			return
+		}
+
+		titleElement = CreateElement("title")
+		AppendChild(head, titleElement)
+	}
+
+	SetTextContent(titleElement, title)
+}
+
+// BaseURI returns the base URL of the document, which is the href of the
+// first <base> element resolved against the document URL. If the document
+// doesn't have any <base> element, the document URL is returned instead.
+func (d *Document) BaseURI() string {
+	base := QuerySelector(d.Node, "base[href]")
+	if base == nil {
+		return d.URL
+	}
+
+	href := strings.TrimSpace(GetAttribute(base, "href"))
+	hrefURL, err := url.Parse(href)
+	if err != nil {
+		return d.URL
+	}
+
+	docURL, err := url.Parse(d.URL)
+	if err != nil {
+		return hrefURL.String()
+	}
+
+	return docURL.ResolveReference(hrefURL).String()
+}
+
+// CharacterSet returns the name of the character encoding that used by the
+// original document before it's converted into UTF-8 while parsing.
+func (d *Document) CharacterSet() string {
+	return d.charset
+}
+
+// SetMetaCharset sets the character encoding that declared by the document,
+// i.e. the charset of <meta charset> or <meta http-equiv="Content-Type">. The
+// first declaration is updated and the rest is removed, since a document can
+// only declare one encoding. If there are none, <meta charset> will be created
+// at the start of the <head> element.
+func (d *Document) SetMetaCharset(charset string) {
+	var declared bool
+	for _, meta := range GetElementsByTagName(d.Node, "meta") {
+		isCharset := HasAttribute(meta, "charset")
+		isContentType := strings.EqualFold(strings.TrimSpace(GetAttribute(meta, "http-equiv")), "content-type")
+		if !isCharset && !isContentType {
+			continue
+		}
+
+		if declared {
+			Remove(meta)
+			continue
+		}
+
+		if isCharset {
+			SetAttribute(meta, "charset", charset)
+		} else {
+			SetAttribute(meta, "content", "text/html; charset="+charset)
+		}
+		declared = true
+	}
+
+	if head := d.Head(); !declared && head != nil {
+		meta := CreateElement("meta")
+		SetAttribute(meta, "charset", charset)
+		PrependChild(head, meta)
+	}
+}
+
+// DocType returns the doctype node of the document, or nil if there are none.
+func (d *Document) DocType() *html.Node {
+	for child := d.Node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.DoctypeNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// CreateElement creates a new ElementNode with specified tag.
+func (d *Document) CreateElement(tagName string) *html.Node {
+	return CreateElement(tagName)
+}
+
+// CreateTextNode creates a new Text node.
+func (d *Document) CreateTextNode(data string) *html.Node {
+	return CreateTextNode(data)
+}
+
+// CreateComment creates a new Comment node.
+func (d *Document) CreateComment(data string) *html.Node {
+	return CreateComment(data)
+}
+
+// CreateDocumentFragment creates a new empty DocumentFragment.
+func (d *Document) CreateDocumentFragment() *html.Node {
+	return CreateDocumentFragment()
+}
+
+func (d *Document) titleElement() *html.Node {
+	// Make sure to skip <title> that used inside SVG
+	for _, title := range GetElementsByTagName(d.Node, "title") {
+		if title.Namespace == "" {
+			return title
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package dom_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+func TestDocumentHeadBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		wantHead   string
+		wantBody   string
+	}{{
+		name:       "complete document",
+		htmlSource: `<html><head><title>Hello</title></head><body><p>World</p></body></html>`,
+		wantHead:   "<head><title>Hello</title></head>",
+		wantBody:   "<body><p>World</p></body>",
+	}, {
+		name:       "implied head and body",
+		htmlSource: `<p>World</p>`,
+		wantHead:   "<head></head>",
+		wantBody:   "<body><p>World</p></body>",
+	}, {
+		name:       "frameset document",
+		htmlSource: `<html><head></head><frameset><frame src="a.html"></frameset></html>`,
+		wantHead:   "<head></head>",
+		wantBody:   `<frameset><frame src="a.html"></frame></frameset>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.FastParseDocument(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("FastParseDocument(), failed to parse: %v", err)
+			}
+
+			if got := dom.OuterHTML(doc.Head()); got != tt.wantHead {
+				t.Errorf("Head() = %v, want %v", got, tt.wantHead)
+			}
+
+			if got := dom.OuterHTML(doc.Body()); got != tt.wantBody {
+				t.Errorf("Body() = %v, want %v", got, tt.wantBody)
+			}
+
+			if got := doc.DocumentElement(); got != dom.DocumentElement(doc.Node) {
+				t.Errorf("DocumentElement() = %v, want %v", got, dom.DocumentElement(doc.Node))
+			}
+		})
+	}
+}
+
+func TestDocumentTitle(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "simple title",
+		htmlSource: `<title>Hello World</title>`,
+		want:       "Hello World",
+	}, {
+		name:       "title with excess whitespace",
+		htmlSource: "<title>\n  Hello \t  World  </title>",
+		want:       "Hello World",
+	}, {
+		name:       "no title",
+		htmlSource: `<p>Hello World</p>`,
+		want:       "",
+	}, {
+		name:       "skip svg title",
+		htmlSource: `<svg><title>Icon</title></svg><title>Hello World</title>`,
+		want:       "Hello World",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.FastParseDocument(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("Title(), failed to parse: %v", err)
+			}
+
+			if got := doc.Title(); got != tt.want {
+				t.Errorf("Title() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentSetTitle(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "replace existing title",
+		htmlSource: `<title>Old</title>`,
+		want:       "<head><title>New</title></head>",
+	}, {
+		name:       "create missing title",
+		htmlSource: `<meta charset="utf-8"/>`,
+		want:       `<head><meta charset="utf-8"/><title>New</title></head>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.FastParseDocument(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("SetTitle(), failed to parse: %v", err)
+			}
+
+			doc.SetTitle("New")
+			if got := dom.OuterHTML(doc.Head()); got != tt.want {
+				t.Errorf("SetTitle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentBaseURI(t *testing.T) {
+	tests := []struct {
+		name       string
+		docURL     string
+		htmlSource string
+		want       string
+	}{{
+		name:       "no base element",
+		docURL:     "https://example.com/blog/post.html",
+		htmlSource: `<p>Hello</p>`,
+		want:       "https://example.com/blog/post.html",
+	}, {
+		name:       "absolute base element",
+		docURL:     "https://example.com/blog/post.html",
+		htmlSource: `<base href="https://cdn.example.com/assets/">`,
+		want:       "https://cdn.example.com/assets/",
+	}, {
+		name:       "relative base element",
+		docURL:     "https://example.com/blog/post.html",
+		htmlSource: `<base href="../static/">`,
+		want:       "https://example.com/static/",
+	}, {
+		name:       "base without href",
+		docURL:     "https://example.com/blog/post.html",
+		htmlSource: `<base target="_blank"><base href="/root/">`,
+		want:       "https://example.com/root/",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.FastParseDocument(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("BaseURI(), failed to parse: %v", err)
+			}
+
+			doc.URL = tt.docURL
+			if got := doc.BaseURI(); got != tt.want {
+				t.Errorf("BaseURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentCharacterSet(t *testing.T) {
+	htmlSource := `<html><head><meta charset="shift_jis"><title>日本語のページ</title></head>` +
+		`<body><p>これは日本語で書かれたページです。文字コードの検出を確認します。</p></body></html>`
+
+	encoded, _, err := transform.String(japanese.ShiftJIS.NewEncoder(), htmlSource)
+	if err != nil {
+		t.Fatalf("CharacterSet(), failed to encode: %v", err)
+	}
+
+	doc, err := dom.ParseDocument(bytes.NewReader([]byte(encoded)))
+	if err != nil {
+		t.Fatalf("CharacterSet(), failed to parse: %v", err)
+	}
+
+	if got := doc.CharacterSet(); got != "shift_jis" {
+		t.Errorf("CharacterSet() = %v, want %v", got, "shift_jis")
+	}
+
+	if got := doc.Title(); got != "日本語のページ" {
+		t.Errorf("Title() = %v, want %v", got, "日本語のページ")
+	}
+
+	fastDoc, err := dom.FastParseDocument(strings.NewReader(htmlSource))
+	if err != nil {
+		t.Fatalf("CharacterSet(), failed to parse: %v", err)
+	}
+
+	if got := fastDoc.CharacterSet(); got != "utf-8" {
+		t.Errorf("CharacterSet() = %v, want %v", got, "utf-8")
+	}
+}
+
+func TestDocumentSetMetaCharset(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "meta charset",
+		htmlSource: `<meta charset="shift_jis"><title>Page</title>`,
+		want:       `<head><meta charset="utf-8"/><title>Page</title></head>`,
+	}, {
+		name:       "meta http-equiv",
+		htmlSource: `<meta http-equiv="Content-Type" content="text/html; charset=euc-jp">`,
+		want:       `<head><meta http-equiv="Content-Type" content="text/html; charset=utf-8"/></head>`,
+	}, {
+		name:       "multiple declarations",
+		htmlSource: `<meta charset="shift_jis"><meta http-equiv="content-type" content="text/html; charset=euc-jp">`,
+		want:       `<head><meta charset="utf-8"/></head>`,
+	}, {
+		name:       "missing declaration",
+		htmlSource: `<title>Page</title>`,
+		want:       `<head><meta charset="utf-8"/><title>Page</title></head>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.FastParseDocument(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("SetMetaCharset(), failed to parse: %v", err)
+			}
+
+			doc.SetMetaCharset("utf-8")
+			if got := dom.OuterHTML(doc.Head()); got != tt.want {
+				t.Errorf("SetMetaCharset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDocumentRewriteMetaCharset(t *testing.T) {
+	htmlSource := `<html><head><meta charset="shift_jis"><title>日本語のページ</title></head>` +
+		`<body><p>これは日本語で書かれたページです。文字コードの検出を確認します。</p></body></html>`
+
+	encoded, _, err := transform.String(japanese.ShiftJIS.NewEncoder(), htmlSource)
+	if err != nil {
+		t.Fatalf("ParseDocumentWithOptions(), failed to encode: %v", err)
+	}
+
+	opts := dom.ParseOptions{RewriteMetaCharset: true}
+	doc, err := dom.ParseDocumentWithOptions(strings.NewReader(encoded), opts)
+	if err != nil {
+		t.Fatalf("ParseDocumentWithOptions(), failed to parse: %v", err)
+	}
+
+	if got := doc.CharacterSet(); got != "shift_jis" {
+		t.Errorf("CharacterSet() = %v, want %v", got, "shift_jis")
+	}
+
+	want := `<head><meta charset="utf-8"/><title>日本語のページ</title></head>`
+	if got := dom.OuterHTML(doc.Head()); got != want {
+		t.Errorf("ParseDocumentWithOptions() head = %v, want %v", got, want)
+	}
+}
+
+func TestDocumentDocType(t *testing.T) {
+	doc, err := dom.FastParseDocument(strings.NewReader(`<!DOCTYPE html><p>Hello</p>`))
+	if err != nil {
+		t.Fatalf("DocType(), failed to parse: %v", err)
+	}
+
+	if got := dom.OuterHTML(doc.DocType()); got != "<!DOCTYPE html>" {
+		t.Errorf("DocType() = %v, want %v", got, "<!DOCTYPE html>")
+	}
+
+	doc, err = dom.FastParseDocument(strings.NewReader(`<p>Hello</p>`))
+	if err != nil {
+		t.Fatalf("DocType(), failed to parse: %v", err)
+	}
+
+	if got := doc.DocType(); got != nil {
+		t.Errorf("DocType() = %v, want nil", dom.OuterHTML(got))
+	}
+}
+
+func TestDocumentCreateNodes(t *testing.T) {
+	doc, err := dom.FastParseDocument(strings.NewReader(`<p>Hello</p>`))
+	if err != nil {
+		t.Fatalf("CreateElement(), failed to parse: %v", err)
+	}
+
+	div := doc.CreateElement("div")
+	dom.AppendChild(div, doc.CreateTextNode("World"))
+	dom.AppendChild(div, doc.CreateComment("note"))
+	dom.AppendChild(doc.Body(), div)
+
+	want := "<body><p>Hello</p><div>World<!--note--></div></body>"
+	if got := dom.OuterHTML(doc.Body()); got != want {
+		t.Errorf("CreateElement() = %v, want %v", got, want)
+	}
+}
@@ -2,18 +2,12 @@ package dom
 
 import (
 	"bytes"
-	"regexp"
+	"io"
 	"strings"
 
 	"github.com/andybalholm/cascadia"
 	"golang.org/x/net/html"
-)
-
-var (
-	rxPunctuation      = regexp.MustCompile(`\s+([.?!,;])\s*(\S*)`)
-	rxTempNewline      = regexp.MustCompile(`\s*\|\\/\|\s*`)
-	rxDisplayNone      = regexp.MustCompile(`(?i)display:\s*none`)
-	rxVisibilityHidden = regexp.MustCompile(`(?i)visibility:\s*(:?hidden|collapse)`)
+	"golang.org/x/net/html/atom"
 )
 
 // QuerySelectorAll returns array of document's elements that match
@@ -159,6 +153,74 @@ func CreateTextNode(data string) *html.Node {
 	}
 }
 
+// CreateComment creates a new Comment node.
+func CreateComment(data string) *html.Node {
+	return &html.Node{
+		Type: html.CommentNode,
+		Data: data,
+	}
+}
+
+// CreateDocumentType creates a new DocumentType node with the specified
+// name, public ID and system ID. Empty ID will be omitted.
+func CreateDocumentType(name, publicID, systemID string) *html.Node {
+	doctype := &html.Node{
+		Type: html.DoctypeNode,
+		Data: name,
+	}
+
+	if publicID != "" {
+		doctype.Attr = append(doctype.Attr, html.Attribute{Key: "public", Val: publicID})
+	}
+
+	if systemID != "" {
+		doctype.Attr = append(doctype.Attr, html.Attribute{Key: "system", Val: systemID})
+	}
+
+	return doctype
+}
+
+// CreateHTMLDocument creates a new Document node with the standard
+// skeleton, i.e. doctype, <html>, <head> and <body>. If title is not
+// empty, a <title> element will be added into the <head> as well.
+func CreateHTMLDocument(title string) *html.Node {
+	doc := &html.Node{Type: html.DocumentNode}
+	doc.AppendChild(CreateDocumentType("html", "", ""))
+
+	root := CreateElement("html")
+	head := CreateElement("head")
+	body := CreateElement("body")
+	doc.AppendChild(root)
+	root.AppendChild(head)
+	root.AppendChild(body)
+
+	if title != "" {
+		titleElement := CreateElement("title")
+		titleElement.AppendChild(CreateTextNode(title))
+		head.AppendChild(titleElement)
+	}
+
+	return doc
+}
+
+// CreateDocumentFragment creates a new empty DocumentFragment. Since html.Node
+// doesn't have fragment type, it's represented as DocumentNode with its data
+// set to "#document-fragment". When a fragment is inserted using AppendChild,
+// PrependChild or ReplaceChild, its children are moved into the new position
+// and the fragment itself is left empty.
+func CreateDocumentFragment() *html.Node {
+	return &html.Node{
+		Type: html.DocumentNode,
+		Data: documentFragmentData,
+	}
+}
+
+// IsDocumentFragment check whether a node is a DocumentFragment that
+// created using CreateDocumentFragment.
+func IsDocumentFragment(node *html.Node) bool {
+	return node != nil && node.Type == html.DocumentNode && node.Data == documentFragmentData
+}
+
 // TagName returns the tag name of a Node.
 // If it's not ElementNode, return empty string.
 func TagName(node *html.Node) string {
@@ -175,7 +237,10 @@ func TagName(node *html.Node) string {
 
 // GetAttribute returns the value of a specified attribute on
 // the element. If the given attribute does not exist, the value
-// returned will be an empty string.
+// returned will be an empty string. The attribute is matched by its
+// name regardless of its namespace, so "href" also matches xlink:href
+// of parsed SVG. Use GetAttributeNS to look up the attribute by its
+// namespace.
 func GetAttribute(node *html.Node, attrName string) string {
 	for i := 0; i < len(node.Attr); i++ {
 		if node.Attr[i].Key == attrName {
@@ -186,7 +251,8 @@ func GetAttribute(node *html.Node, attrName string) string {
 }
 
 // SetAttribute sets attribute for node. If attribute already exists,
-// it will be replaced.
+// it will be replaced. New attribute is created without namespace, so
+// use SetAttributeNS to create namespaced attribute like xlink:href.
 func SetAttribute(node *html.Node, attrName string, attrValue string) {
 	attrIdx := -1
 	for i := 0; i < len(node.Attr); i++ {
@@ -235,7 +301,8 @@ func HasAttribute(node *html.Node, attrName string) bool {
 }
 
 // TextContent returns the text content of the specified node,
-// and all its descendants.
+// and all its descendants. Use TextContentWithOptions to control
+// how the ruby annotation is extracted.
 func TextContent(node *html.Node) string {
 	var buffer bytes.Buffer
 	var finder func(*html.Node)
@@ -257,59 +324,33 @@ func TextContent(node *html.Node) string {
 // InnerText in JS used to capture text from an element while excluding text from hidden
 // children. A child is hidden if it's computed width is 0, whether because its CSS (e.g
 // `display: none`, `visibility: hidden`, etc), or if the child has `hidden` attribute.
-// Since we can't compute stylesheet, we only look at `hidden` attribute and inline style.
+// Since we can't do layout, the visibility is decided using the computed style from
+// the inline style of the node and its ancestors. To use the <style> elements in the
+// document as well, pass a Cascade to InnerTextWithOptions.
 //
 // Besides excluding text from hidden children, difference between this function and
-// `TextContent` is the latter will skip <br> tag while this function will preserve
-// <br> as newline.
+// `TextContent` is the latter returns the raw text while this function returns the text
+// as it's rendered, following the "rendered text collection steps" in HTML specification:
+// whitespace is collapsed except in `pre`-styled contexts, <br> is preserved as newline,
+// block elements are separated by newline, paragraphs by blank line, table cells by tab
+// and table rows by newline. If the node itself is not rendered (e.g. it's hidden),
+// its text content is returned instead.
+//
+// Collapsed whitespace is also script-aware: line break between Chinese or Japanese
+// text is removed instead of converted into space, and there is no space around
+// fullwidth punctuation (e.g. "。" and "，") or before closing punctuation,
+// including the Arabic and Hebrew ones. Use InnerTextWithOptions to control
+// how the ruby annotation is extracted.
 func InnerText(node *html.Node) string {
-	var buffer bytes.Buffer
-	var finder func(*html.Node)
-
-	finder = func(n *html.Node) {
-		switch n.Type {
-		case html.TextNode:
-			buffer.WriteString(" " + n.Data + " ")
-
-		case html.ElementNode:
-			if n.Data == "br" {
-				buffer.WriteString(`|\/|`)
-				return
-			}
-
-			if HasAttribute(n, "hidden") {
-				return
-			}
-
-			styleAttr := GetAttribute(n, "style")
-			if rxDisplayNone.MatchString(styleAttr) || rxVisibilityHidden.MatchString(styleAttr) {
-				return
-			}
-		}
-
-		for child := n.FirstChild; child != nil; child = child.NextSibling {
-			finder(child)
-		}
-	}
-
-	finder(node)
-	text := buffer.String()
-	text = strings.Join(strings.Fields(text), " ")
-	text = rxPunctuation.ReplaceAllString(text, "$1 $2")
-	text = rxTempNewline.ReplaceAllString(text, "\n")
-	return text
+	return renderedText(node, cascadeFor(nil), TextOptions{})
 }
 
 // OuterHTML returns an HTML serialization of the element and its descendants.
-// The returned HTML value is escaped.
+// The returned HTML value is escaped. Returns empty string if the node can't
+// be rendered, use WriteOuterHTML to get the error.
 func OuterHTML(node *html.Node) string {
-	if node == nil {
-		return ""
-	}
-
 	var buffer bytes.Buffer
-	err := html.Render(&buffer, node)
-	if err != nil {
+	if err := WriteOuterHTML(&buffer, node); err != nil {
 		return ""
 	}
 
@@ -317,23 +358,60 @@ func OuterHTML(node *html.Node) string {
 }
 
 // InnerHTML returns the HTML content (inner HTML) of an element.
-// The returned HTML value is escaped.
+// The returned HTML value is escaped, and the whitespace around it
+// is trimmed. Returns empty string if the content can't be rendered,
+// use WriteInnerHTML to get the error.
 func InnerHTML(node *html.Node) string {
-	var err error
+	return InnerHTMLWithOptions(node, InnerHTMLOptions{})
+}
+
+// InnerHTMLOptions is the options for InnerHTMLWithOptions.
+type InnerHTMLOptions struct {
+	// Exact keeps the whitespace around the content as it is,
+	// so the result is the same as written by WriteInnerHTML.
+	Exact bool
+}
+
+// InnerHTMLWithOptions is like InnerHTML, but returns
+// the HTML content following the specified options.
+func InnerHTMLWithOptions(node *html.Node, opts InnerHTMLOptions) string {
 	var buffer bytes.Buffer
+	if err := WriteInnerHTML(&buffer, node); err != nil {
+		return ""
+	}
+
+	if opts.Exact {
+		return buffer.String()
+	}
+	return strings.TrimSpace(buffer.String())
+}
 
+// WriteOuterHTML writes an HTML serialization of the element and its
+// descendants into the writer. Unlike OuterHTML, the HTML is streamed
+// to the writer and any error while rendering is returned.
+func WriteOuterHTML(w io.Writer, node *html.Node) error {
 	if node == nil {
-		return ""
+		return nil
+	}
+
+	return html.Render(w, node)
+}
+
+// WriteInnerHTML writes the HTML content (inner HTML) of an element into
+// the writer. Unlike InnerHTML, the content is written exactly as it is
+// without trimming its whitespace, and any error while rendering is returned.
+func WriteInnerHTML(w io.Writer, node *html.Node) error {
+	if node == nil {
+		return nil
 	}
 
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		err = html.Render(&buffer, child)
-		if err != nil {
-			return ""
+		if err := html.Render(w, child); err != nil {
+			return err
 		}
 	}
 
-	return strings.TrimSpace(buffer.String())
+	return nil
 }
 
 // DocumentElement returns the Element that is the root element
@@ -398,6 +476,72 @@ func FirstElementChild(node *html.Node) *html.Node {
 	return nil
 }
 
+// LastElementChild returns the object's last child Element,
+// or nil if there are no child elements.
+func LastElementChild(node *html.Node) *html.Node {
+	for child := node.LastChild; child != nil; child = child.PrevSibling {
+		if child.Type == html.ElementNode {
+			return child
+		}
+	}
+	return nil
+}
+
+// ChildElementCount returns the number of child elements of the node.
+func ChildElementCount(node *html.Node) int {
+	count := 0
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			count++
+		}
+	}
+	return count
+}
+
+// HasChildNodes returns a Boolean value indicating whether
+// the node has any child nodes or not.
+func HasChildNodes(node *html.Node) bool {
+	return node.FirstChild != nil
+}
+
+// ParentElement returns the parent of the node if it's an Element,
+// or nil if the node has no parent or its parent is not an Element.
+func ParentElement(node *html.Node) *html.Node {
+	if node.Parent != nil && node.Parent.Type == html.ElementNode {
+		return node.Parent
+	}
+	return nil
+}
+
+// GetRootNode returns the root of the tree which contains the node.
+// If the node doesn't have any parent, the node itself is returned.
+func GetRootNode(node *html.Node) *html.Node {
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root
+}
+
+// IsConnected returns a Boolean value indicating whether the node
+// is (directly or indirectly) connected to a document.
+func IsConnected(node *html.Node) bool {
+	root := GetRootNode(node)
+	return root.Type == html.DocumentNode && !IsDocumentFragment(root)
+}
+
+// Contains returns a Boolean value indicating whether other is an inclusive
+// descendant of the node, i.e. the node itself, one of its direct children,
+// one of the children's direct children, and so on.
+func Contains(node *html.Node, other *html.Node) bool {
+	for ; other != nil; other = other.Parent {
+		if other == node {
+			return true
+		}
+	}
+	return false
+}
+
 // PreviousElementSibling returns the the Element immediately prior
 // to the specified one in its parent's children list, or null if
 // the specified element is the first one in the list.
@@ -425,12 +569,12 @@ func NextElementSibling(node *html.Node) *html.Node {
 // AppendChild adds a node to the end of the list of children of a
 // specified parent node. If the given child is a reference to an
 // existing node in the document, AppendChild() moves it from its
-// current position to the new position.
+// current position to the new position. If the child is a
+// DocumentFragment, its children will be moved instead.
 func AppendChild(node *html.Node, child *html.Node) {
 	// Make sure node is not void
-	if !IsVoidElement(node) {
-		DetachChild(child)
-		node.AppendChild(child)
+	if canHaveChildren(node) {
+		insertBefore(node, child, nil)
 	}
 }
 
@@ -438,39 +582,129 @@ func AppendChild(node *html.Node, child *html.Node) {
 // beginning of the list of children of a specified parent node.
 func PrependChild(node *html.Node, child *html.Node) {
 	// Make sure node is not void
-	if !IsVoidElement(node) {
-		DetachChild(child)
-		if node.FirstChild != nil {
-			node.InsertBefore(child, node.FirstChild)
-		} else {
-			node.AppendChild(child)
-		}
+	if canHaveChildren(node) {
+		insertBefore(node, child, node.FirstChild)
 	}
 }
 
 // ReplaceChild replaces a child node within the given (parent) node.
 // If the new child is already exist in document, ReplaceChild() will move it
-// from its current position to replace old child. Returns both the new and old child.
-//
-// TODO: I'm note sure but I *think* there are some issues here. Check later I guess.
+// from its current position to replace old child. If the new child is a
+// DocumentFragment, the old child will be replaced by the fragment's children.
+// Returns both the new and old child.
 func ReplaceChild(parent *html.Node, newChild *html.Node, oldChild *html.Node) (*html.Node, *html.Node) {
 	// Make sure parent is specified and not void
-	if parent == nil && !IsVoidElement(parent) {
+	if parent == nil || !canHaveChildren(parent) {
 		return newChild, oldChild
 	}
 
 	// Make sure the specified parent IS the parent of the old child
-	if oldChild.Parent != parent {
+	if oldChild.Parent != parent || newChild == oldChild || Contains(newChild, parent) {
 		return newChild, oldChild
 	}
 
-	// Detach the new child
-	DetachChild(newChild)
-	parent.InsertBefore(newChild, oldChild)
+	insertBefore(parent, newChild, oldChild)
 	parent.RemoveChild(oldChild)
 	return newChild, oldChild
 }
 
+// Append inserts a set of nodes after the last child of the node. The
+// nodes can be either *html.Node or string, which will be inserted as
+// Text node. Values with any other type are ignored. Does nothing if one
+// of the nodes is the node itself or its ancestor, since a node can't be
+// inserted into its own descendant.
+func Append(node *html.Node, nodes ...interface{}) {
+	if canHaveChildren(node) && isHierarchyAllowed(node, nodes) {
+		insertBefore(node, convertNodesIntoNode(nodes), nil)
+	}
+}
+
+// Prepend inserts a set of nodes before the first child of the node.
+// Like Append, the nodes can be either *html.Node or string, and
+// nothing is inserted if one of them is the node or its ancestor.
+func Prepend(node *html.Node, nodes ...interface{}) {
+	if canHaveChildren(node) && isHierarchyAllowed(node, nodes) {
+		// The conversion might move the first child, so it must be done
+		// before looking up the first child
+		newNode := convertNodesIntoNode(nodes)
+		insertBefore(node, newNode, node.FirstChild)
+	}
+}
+
+// Before inserts a set of nodes in the children list of the node's
+// parent, just before the node. Like Append, the nodes can be either
+// *html.Node or string. Does nothing if the node doesn't have parent,
+// or if one of the nodes is the parent or its ancestor.
+func Before(node *html.Node, nodes ...interface{}) {
+	parent := node.Parent
+	if parent == nil || !isHierarchyAllowed(parent, nodes) {
+		return
+	}
+
+	// Find the first preceding sibling that is not one of the nodes
+	viablePrevSibling := node.PrevSibling
+	for viablePrevSibling != nil && includeValue(nodes, viablePrevSibling) {
+		viablePrevSibling = viablePrevSibling.PrevSibling
+	}
+
+	newNode := convertNodesIntoNode(nodes)
+	if viablePrevSibling == nil {
+		insertBefore(parent, newNode, parent.FirstChild)
+	} else {
+		insertBefore(parent, newNode, viablePrevSibling.NextSibling)
+	}
+}
+
+// After inserts a set of nodes in the children list of the node's
+// parent, just after the node. Like Append, the nodes can be either
+// *html.Node or string. Does nothing if the node doesn't have parent,
+// or if one of the nodes is the parent or its ancestor.
+func After(node *html.Node, nodes ...interface{}) {
+	parent := node.Parent
+	if parent == nil || !isHierarchyAllowed(parent, nodes) {
+		return
+	}
+
+	// Find the first following sibling that is not one of the nodes
+	viableNextSibling := node.NextSibling
+	for viableNextSibling != nil && includeValue(nodes, viableNextSibling) {
+		viableNextSibling = viableNextSibling.NextSibling
+	}
+
+	insertBefore(parent, convertNodesIntoNode(nodes), viableNextSibling)
+}
+
+// ReplaceWith replaces the node in the children list of its parent with
+// a set of nodes. Like Append, the nodes can be either *html.Node or string.
+// Does nothing if the node doesn't have parent, or if one of
+// the nodes is the parent or its ancestor.
+func ReplaceWith(node *html.Node, nodes ...interface{}) {
+	parent := node.Parent
+	if parent == nil || !isHierarchyAllowed(parent, nodes) {
+		return
+	}
+
+	// Find the first following sibling that is not one of the nodes
+	viableNextSibling := node.NextSibling
+	for viableNextSibling != nil && includeValue(nodes, viableNextSibling) {
+		viableNextSibling = viableNextSibling.NextSibling
+	}
+
+	// The node itself might be one of the new nodes, in which case
+	// it will be moved into the fragment before we replace it
+	newNode := convertNodesIntoNode(nodes)
+	if node.Parent == parent {
+		ReplaceChild(parent, newNode, node)
+	} else {
+		insertBefore(parent, newNode, viableNextSibling)
+	}
+}
+
+// Remove removes the node from the children list of its parent.
+func Remove(node *html.Node) {
+	DetachChild(node)
+}
+
 // IncludeNode determines if node is included inside nodeList.
 func IncludeNode(nodeList []*html.Node, node *html.Node) bool {
 	for i := 0; i < len(nodeList); i++ {
@@ -485,10 +719,11 @@ func IncludeNode(nodeList []*html.Node, node *html.Node) bool {
 // However, it will be detached from the original's parents and siblings.
 func Clone(src *html.Node, deep bool) *html.Node {
 	clone := &html.Node{
-		Type:     src.Type,
-		DataAtom: src.DataAtom,
-		Data:     src.Data,
-		Attr:     append([]html.Attribute{}, src.Attr...),
+		Type:      src.Type,
+		DataAtom:  src.DataAtom,
+		Data:      src.Data,
+		Namespace: src.Namespace,
+		Attr:      append([]html.Attribute{}, src.Attr...),
 	}
 
 	if deep {
@@ -549,12 +784,34 @@ func SetTextContent(node *html.Node, text string) {
 	})
 }
 
-// SetInnerHTML sets inner HTML of the specified node.
+// SetInnerHTML sets inner HTML of the specified node. If the node is an
+// element, the raw HTML is parsed using the node as its context, so content
+// of foreign element like <svg> and <math> keep their namespace.
 func SetInnerHTML(node *html.Node, rawHTML string) {
 	// Parse raw HTML
-	parsedHTML, err := html.Parse(strings.NewReader(rawHTML))
-	if err != nil || parsedHTML == nil {
-		return
+	var newChildren []*html.Node
+	if node.Type == html.ElementNode {
+		context := &html.Node{
+			Type:      html.ElementNode,
+			DataAtom:  atom.Lookup([]byte(node.Data)),
+			Data:      node.Data,
+			Namespace: node.Namespace,
+		}
+
+		nodes, err := html.ParseFragment(strings.NewReader(rawHTML), context)
+		if err != nil {
+			return
+		}
+		newChildren = nodes
+	} else {
+		parsedHTML, err := html.Parse(strings.NewReader(rawHTML))
+		if err != nil || parsedHTML == nil {
+			return
+		}
+
+		if body := QuerySelector(parsedHTML, "body"); body != nil {
+			newChildren = ChildNodes(body)
+		}
 	}
 
 	// Remove node's current children
@@ -566,13 +823,8 @@ func SetInnerHTML(node *html.Node, rawHTML string) {
 	}
 
 	// Put content of parsed HTML to the node
-	if body := QuerySelector(parsedHTML, "body"); body != nil {
-		bodyChild := body.FirstChild
-		for bodyChild != nil {
-			nextSibling := bodyChild.NextSibling
-			AppendChild(node, bodyChild)
-			bodyChild = nextSibling
-		}
+	for _, newChild := range newChildren {
+		AppendChild(node, newChild)
 	}
 }
 
@@ -595,6 +847,8 @@ func IsVoidElement(n *html.Node) bool {
 	}
 }
 
+// DetachChild removes the child from its parent and siblings, so it can be
+// inserted into another position.
 func DetachChild(child *html.Node) {
 	if child.Parent != nil || child.PrevSibling != nil || child.NextSibling != nil {
 		if child.Parent != nil {
@@ -620,3 +874,100 @@ func DetachChild(child *html.Node) {
 		child.NextSibling = nil
 	}
 }
+
+// documentFragmentData is the data of DocumentNode which marks it
+// as a DocumentFragment.
+const documentFragmentData = "#document-fragment"
+
+// canHaveChildren check whether a node is allowed to have children, i.e.
+// it's either a non-void element, a document or a document fragment.
+func canHaveChildren(node *html.Node) bool {
+	if node == nil {
+		return false
+	}
+
+	if node.Type == html.DocumentNode {
+		return true
+	}
+
+	return !IsVoidElement(node)
+}
+
+// insertBefore inserts child into parent before the reference node. If
+// reference is nil, child is appended to the end of the parent's children.
+// If child is a DocumentFragment, its children are inserted instead.
+func insertBefore(parent, child, reference *html.Node) {
+	if IsDocumentFragment(child) {
+		for fragChild := child.FirstChild; fragChild != nil; {
+			nextSibling := fragChild.NextSibling
+			insertBefore(parent, fragChild, reference)
+			fragChild = nextSibling
+		}
+		return
+	}
+
+	// The child can't be inserted into itself or its descendant
+	if Contains(child, parent) {
+		return
+	}
+
+	// If the child is the reference itself, use its next sibling instead
+	if reference == child {
+		reference = child.NextSibling
+	}
+
+	DetachChild(child)
+	if reference != nil {
+		parent.InsertBefore(child, reference)
+	} else {
+		parent.AppendChild(child)
+	}
+}
+
+// isHierarchyAllowed check whether the nodes can be inserted into the parent,
+// i.e. none of them is the parent itself or its ancestor, which otherwise
+// would put the node inside its own descendant.
+func isHierarchyAllowed(parent *html.Node, nodes []interface{}) bool {
+	for _, node := range nodes {
+		if n, ok := node.(*html.Node); ok && n != nil && Contains(n, parent) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertNodesIntoNode converts a set of nodes and strings into a single
+// node. If there are more than one node, they will be put into a fragment.
+func convertNodesIntoNode(nodes []interface{}) *html.Node {
+	var convertedNodes []*html.Node
+	for _, node := range nodes {
+		switch v := node.(type) {
+		case *html.Node:
+			if v != nil {
+				convertedNodes = append(convertedNodes, v)
+			}
+		case string:
+			convertedNodes = append(convertedNodes, CreateTextNode(v))
+		}
+	}
+
+	if len(convertedNodes) == 1 {
+		return convertedNodes[0]
+	}
+
+	fragment := CreateDocumentFragment()
+	for _, node := range convertedNodes {
+		insertBefore(fragment, node, nil)
+	}
+	return fragment
+}
+
+// includeValue determines if node is included inside a set of values.
+func includeValue(values []interface{}, node *html.Node) bool {
+	for _, value := range values {
+		if n, ok := value.(*html.Node); ok && n == node {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package dom_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -299,6 +300,158 @@ func TestCreateTextNode(t *testing.T) {
 	}
 }
 
+func TestCreateComment(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{{
+		name: "simple comment",
+		data: "hello world",
+		want: "<!--hello world-->",
+	}, {
+		name: "empty comment",
+		data: "",
+		want: "<!---->",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := dom.CreateComment(tt.data)
+			if node.Type != html.CommentNode {
+				t.Errorf("CreateComment() type = %v, want %v", node.Type, html.CommentNode)
+			}
+
+			if outerHTML := dom.OuterHTML(node); outerHTML != tt.want {
+				t.Errorf("CreateComment() = %v, want %v", outerHTML, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateDocumentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		doctype  string
+		publicID string
+		systemID string
+		want     string
+	}{{
+		name:    "html5 doctype",
+		doctype: "html",
+		want:    "<!DOCTYPE html>",
+	}, {
+		name:     "html4 strict doctype",
+		doctype:  "html",
+		publicID: "-//W3C//DTD HTML 4.01//EN",
+		systemID: "http://www.w3.org/TR/html4/strict.dtd",
+		want:     `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
+	}, {
+		name:     "system only doctype",
+		doctype:  "html",
+		systemID: "about:legacy-compat",
+		want:     `<!DOCTYPE html SYSTEM "about:legacy-compat">`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := dom.CreateDocumentType(tt.doctype, tt.publicID, tt.systemID)
+			if outerHTML := dom.OuterHTML(node); outerHTML != tt.want {
+				t.Errorf("CreateDocumentType() = %v, want %v", outerHTML, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateHTMLDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{{
+		name:  "document with title",
+		title: "Hello World",
+		want:  "<!DOCTYPE html><html><head><title>Hello World</title></head><body></body></html>",
+	}, {
+		name:  "document without title",
+		title: "",
+		want:  "<!DOCTYPE html><html><head></head><body></body></html>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := dom.CreateHTMLDocument(tt.title)
+			if outerHTML := dom.OuterHTML(doc); outerHTML != tt.want {
+				t.Errorf("CreateHTMLDocument() = %v, want %v", outerHTML, tt.want)
+			}
+
+			// Make sure the skeleton is usable by Document wrapper
+			if got := dom.NewDocument(doc).Body(); got == nil {
+				t.Errorf("CreateHTMLDocument() doesn't have body")
+			}
+		})
+	}
+}
+
+func TestCreateDocumentFragment(t *testing.T) {
+	newFragment := func() *html.Node {
+		fragment := dom.CreateDocumentFragment()
+		dom.AppendChild(fragment, dom.CreateElement("b"))
+		dom.AppendChild(fragment, dom.CreateTextNode("text"))
+		dom.AppendChild(fragment, dom.CreateElement("i"))
+		return fragment
+	}
+
+	tests := []struct {
+		name   string
+		insert func(div, p, fragment *html.Node)
+		want   string
+	}{{
+		name: "append fragment",
+		insert: func(div, p, fragment *html.Node) {
+			dom.AppendChild(div, fragment)
+		},
+		want: "<div><p>Lonely word</p><span>friend</span><b></b>text<i></i></div>",
+	}, {
+		name: "prepend fragment",
+		insert: func(div, p, fragment *html.Node) {
+			dom.PrependChild(div, fragment)
+		},
+		want: "<div><b></b>text<i></i><p>Lonely word</p><span>friend</span></div>",
+	}, {
+		name: "replace with fragment",
+		insert: func(div, p, fragment *html.Node) {
+			dom.ReplaceChild(div, fragment, p)
+		},
+		want: "<div><b></b>text<i></i><span>friend</span></div>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(`<div><p>Lonely word</p><span>friend</span></div>`)
+			if err != nil {
+				t.Errorf("CreateDocumentFragment(), failed to parse: %v", err)
+			}
+
+			div := doc.FirstChild
+			p := dom.GetElementsByTagName(div, "p")[0]
+			fragment := newFragment()
+			if !dom.IsDocumentFragment(fragment) {
+				t.Errorf("IsDocumentFragment() = false, want true")
+			}
+
+			tt.insert(div, p, fragment)
+			if got := dom.OuterHTML(div); got != tt.want {
+				t.Errorf("CreateDocumentFragment() = %v, want %v", got, tt.want)
+			}
+
+			if fragment.FirstChild != nil {
+				t.Errorf("CreateDocumentFragment() fragment is not emptied: %v", dom.OuterHTML(fragment))
+			}
+		})
+	}
+}
+
 func TestGetAttribute(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -505,6 +658,175 @@ func TestTextContent(t *testing.T) {
 	}
 }
 
+func TestInnerText(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "visible text",
+		htmlSource: "<div><p>Hello</p><p>World</p></div>",
+		want:       "Hello\n\nWorld",
+	}, {
+		name:       "hidden attribute",
+		htmlSource: "<div><p>Hello</p><p hidden>World</p></div>",
+		want:       "Hello",
+	}, {
+		name:       "display none",
+		htmlSource: `<div><p>Hello</p><p style="color: red; DISPLAY : None">World</p></div>`,
+		want:       "Hello",
+	}, {
+		name:       "important display none",
+		htmlSource: `<div><p>Hello</p><p style="display:none!important">World</p></div>`,
+		want:       "Hello",
+	}, {
+		name:       "visibility hidden",
+		htmlSource: `<div><p>Hello</p><p style="visibility: collapse">World</p></div>`,
+		want:       "Hello",
+	}, {
+		name:       "display none inside url",
+		htmlSource: `<div><p>Hello</p><p style="background: url('display:none.png')">World</p></div>`,
+		want:       "Hello\n\nWorld",
+	}, {
+		name:       "display none inside comment",
+		htmlSource: `<div><p>Hello</p><p style="/* display: none; */ color: red">World</p></div>`,
+		want:       "Hello\n\nWorld",
+	}, {
+		name:       "overridden display none",
+		htmlSource: `<div><p>Hello</p><p style="display: none; display: block">World</p></div>`,
+		want:       "Hello\n\nWorld",
+	}, {
+		name:       "visible child of hidden parent",
+		htmlSource: `<div style="visibility: hidden">Hello <b style="visibility: visible">World</b></div>`,
+		want:       "World",
+	}, {
+		name:       "collapsed whitespace across inline elements",
+		htmlSource: "<div>\n  Hello   <b> big </b>\n <i>world</i> !  \n</div>",
+		want:       "Hello big world!",
+	}, {
+		name:       "line break",
+		htmlSource: "<div>Hello <br> World<br></div>",
+		want:       "Hello\nWorld\n",
+	}, {
+		name:       "block elements",
+		htmlSource: "<div>Title<div>First</div><div><div>Nested</div></div>Last</div>",
+		want:       "Title\nFirst\nNested\nLast",
+	}, {
+		name:       "headings and paragraphs",
+		htmlSource: "<div><h1>Title</h1><p>First paragraph</p><p>Second paragraph</p></div>",
+		want:       "Title\n\nFirst paragraph\n\nSecond paragraph",
+	}, {
+		name:       "list items",
+		htmlSource: "<div><ul>\n<li>One</li>\n<li>Two</li>\n</ul></div>",
+		want:       "One\nTwo",
+	}, {
+		name: "table",
+		htmlSource: "<div><table>\n<thead><tr><th>A</th><th>B</th></tr></thead>\n" +
+			"<tbody><tr><td>1</td> <td>2</td></tr><tr><td>3</td><td>4</td></tr></tbody></table></div>",
+		want: "A\tB\n1\t2\n3\t4",
+	}, {
+		name:       "preformatted text",
+		htmlSource: "<div><p>Code:</p><pre>  if (a) {\n    b();\n  }</pre></div>",
+		want:       "Code:\n\n  if (a) {\n    b();\n  }",
+	}, {
+		name:       "textarea",
+		htmlSource: "<div>Input <textarea>  line 1\n  line 2</textarea></div>",
+		want:       "Input   line 1\n  line 2",
+	}, {
+		name:       "pre-line",
+		htmlSource: "<div style=\"white-space: pre-line\">  Hello   \n   World  </div>",
+		want:       "Hello\nWorld",
+	}, {
+		name:       "excluded script, style and template",
+		htmlSource: "<div>Hello<script>var a;</script><style>p{}</style><template>Hi</template> World</div>",
+		want:       "Hello World",
+	}, {
+		name:       "chinese sentence across line break",
+		htmlSource: "<div>这是一个\n  很长的句子。\n</div>",
+		want:       "这是一个很长的句子。",
+	}, {
+		name:       "japanese inline elements",
+		htmlSource: "<div><span>日本語の</span>\n<b>テキスト</b> 、です</div>",
+		want:       "日本語のテキスト、です",
+	}, {
+		name:       "fullwidth punctuation",
+		htmlSource: "<div>你好 ， 世界 。 「 引用 」</div>",
+		want:       "你好，世界。「引用」",
+	}, {
+		name:       "korean keeps space",
+		htmlSource: "<div>한국어\n문장</div>",
+		want:       "한국어 문장",
+	}, {
+		name:       "latin and cjk keep space",
+		htmlSource: "<div>Go\n语言</div>",
+		want:       "Go 语言",
+	}, {
+		name:       "ascii punctuation",
+		htmlSource: "<div><b>Hello</b> , <i>world</i> !</div>",
+		want:       "Hello, world!",
+	}, {
+		name:       "arabic punctuation",
+		htmlSource: "<div>مرحبا ؟ <b>كيف</b> ، حالك</div>",
+		want:       "مرحبا؟ كيف، حالك",
+	}, {
+		name:       "bidi mark is transparent",
+		htmlSource: "<div>שלום \u200F, עולם</div>",
+		want:       "שלום\u200F, עולם",
+	}, {
+		name:       "zero width space",
+		htmlSource: "<div>abc\u200B\ndef</div>",
+		want:       "abc\u200Bdef",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("InnerText(), failed to parse: %v", err)
+			}
+
+			div := dom.QuerySelector(doc, "div")
+			if got := dom.InnerText(div); got != tt.want {
+				t.Errorf("InnerText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("exclude head", func(t *testing.T) {
+		doc, err := html.Parse(strings.NewReader("<title>Title</title><p>Hello</p>"))
+		if err != nil {
+			t.Errorf("InnerText(), failed to parse: %v", err)
+		}
+
+		if got := dom.InnerText(dom.DocumentElement(doc)); got != "Hello" {
+			t.Errorf("InnerText() = %q, want %q", got, "Hello")
+		}
+
+		title := dom.QuerySelector(doc, "title")
+		if got := dom.InnerText(title); got != "Title" {
+			t.Errorf("InnerText() = %q, want %q", got, "Title")
+		}
+	})
+	t.Run("embedded stylesheet", func(t *testing.T) {
+		htmlSource := `<style>.ad { display: none }</style><div><p>Hello</p><p class="ad">World</p></div>`
+		doc, err := html.Parse(strings.NewReader(htmlSource))
+		if err != nil {
+			t.Errorf("InnerText(), failed to parse: %v", err)
+		}
+
+		// The stylesheet is only used when the cascade is specified
+		div := dom.QuerySelector(doc, "div")
+		if got := dom.InnerText(div); got != "Hello\n\nWorld" {
+			t.Errorf("InnerText() = %q, want %q", got, "Hello\n\nWorld")
+		}
+
+		opts := dom.TextOptions{Cascade: dom.NewCascade(doc, dom.CascadeOptions{})}
+		if got := dom.InnerTextWithOptions(div, opts); got != "Hello" {
+			t.Errorf("InnerTextWithOptions() = %q, want %q", got, "Hello")
+		}
+	})
+}
+
 func TestOuterHTML(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -585,6 +907,90 @@ func TestInnerHTML(t *testing.T) {
 	}
 }
 
+func TestWriteInnerHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "single element",
+		htmlSource: "<h1>Hello</h1>",
+		want:       "Hello",
+	}, {
+		name:       "whitespace around content",
+		htmlSource: "<div>\n  <p>Some element</p>\n</div>",
+		want:       "\n  <p>Some element</p>\n",
+	}, {
+		name:       "empty element",
+		htmlSource: "<div></div>",
+		want:       "",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("WriteInnerHTML(), failed to parse: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := dom.WriteInnerHTML(&sb, doc.FirstChild); err != nil {
+				t.Fatalf("WriteInnerHTML() error = %v", err)
+			}
+
+			if got := sb.String(); got != tt.want {
+				t.Errorf("WriteInnerHTML() = %q, want %q", got, tt.want)
+			}
+
+			exact := dom.InnerHTMLWithOptions(doc.FirstChild, dom.InnerHTMLOptions{Exact: true})
+			if exact != tt.want {
+				t.Errorf("InnerHTMLWithOptions() = %q, want %q", exact, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOuterHTMLError(t *testing.T) {
+	doc, err := parseHTMLSource("<div><p>Hello</p></div>")
+	if err != nil {
+		t.Fatalf("WriteOuterHTML(), failed to parse: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := dom.WriteOuterHTML(&sb, doc.FirstChild); err != nil {
+		t.Fatalf("WriteOuterHTML() error = %v", err)
+	}
+
+	if got, want := sb.String(), "<div><p>Hello</p></div>"; got != want {
+		t.Errorf("WriteOuterHTML() = %q, want %q", got, want)
+	}
+
+	errWrite := errors.New("write failed")
+	if err := dom.WriteOuterHTML(failingWriter{errWrite}, doc.FirstChild); !errors.Is(err, errWrite) {
+		t.Errorf("WriteOuterHTML() error = %v, want %v", err, errWrite)
+	}
+
+	if err := dom.WriteInnerHTML(failingWriter{errWrite}, doc.FirstChild); !errors.Is(err, errWrite) {
+		t.Errorf("WriteInnerHTML() error = %v, want %v", err, errWrite)
+	}
+
+	// Render error is reported instead of returning empty string
+	div := dom.CreateElement("div")
+	dom.AppendChild(div, &html.Node{Type: html.ErrorNode})
+	if err := dom.WriteInnerHTML(&sb, div); err == nil {
+		t.Errorf("WriteInnerHTML() error = nil, want error")
+	}
+}
+
+// failingWriter is a writer that always returns error.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
 func TestId(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -792,6 +1198,422 @@ func TestFirstElementChild(t *testing.T) {
 	}
 }
 
+func TestLastElementChild(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "has no children",
+		htmlSource: "<div></div>",
+		want:       "",
+	}, {
+		name:       "has no element children",
+		htmlSource: "<div>I'm your father.</div>",
+		want:       "",
+	}, {
+		name:       "has one element children",
+		htmlSource: "<div><p>Luke, I'm your father.</p></div>",
+		want:       "<p>Luke, I&#39;m your father.</p>",
+	}, {
+		name:       "has many element children",
+		htmlSource: "<div><p>Luke, I'm your father.</p><p>Nooooo!!</p>Trailing text</div>",
+		want:       "<p>Nooooo!!</p>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("LastElementChild(), failed to parse: %v", err)
+			}
+
+			node := dom.LastElementChild(doc.FirstChild)
+			if got := dom.OuterHTML(node); got != tt.want {
+				t.Errorf("LastElementChild() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChildElementCount(t *testing.T) {
+	tests := []struct {
+		name           string
+		htmlSource     string
+		wantCount      int
+		wantHasContent bool
+	}{{
+		name:           "has no children",
+		htmlSource:     "<div></div>",
+		wantCount:      0,
+		wantHasContent: false,
+	}, {
+		name:           "has no element children",
+		htmlSource:     "<div>I'm your father.</div>",
+		wantCount:      0,
+		wantHasContent: true,
+	}, {
+		name:           "has mixed children",
+		htmlSource:     "<div><p>Luke</p>text<!--comment--><p>Vader</p></div>",
+		wantCount:      2,
+		wantHasContent: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("ChildElementCount(), failed to parse: %v", err)
+			}
+
+			div := doc.FirstChild
+			if got := dom.ChildElementCount(div); got != tt.wantCount {
+				t.Errorf("ChildElementCount() = %v, want %v", got, tt.wantCount)
+			}
+
+			if got := dom.HasChildNodes(div); got != tt.wantHasContent {
+				t.Errorf("HasChildNodes() = %v, want %v", got, tt.wantHasContent)
+			}
+		})
+	}
+}
+
+func TestTreeRelations(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader("<div><p>Hello <b>world</b></p></div>"))
+	if err != nil {
+		t.Errorf("TreeRelations(), failed to parse: %v", err)
+	}
+
+	div := dom.QuerySelector(doc, "div")
+	p := dom.QuerySelector(doc, "p")
+	b := dom.QuerySelector(doc, "b")
+	text := p.FirstChild
+	detached := dom.CreateElement("span")
+	dom.AppendChild(detached, dom.CreateTextNode("alone"))
+
+	fragment := dom.CreateDocumentFragment()
+	inFragment := dom.CreateElement("i")
+	dom.AppendChild(fragment, inFragment)
+
+	t.Run("parent element", func(t *testing.T) {
+		if got := dom.ParentElement(b); got != p {
+			t.Errorf("ParentElement() = %v, want %v", dom.OuterHTML(got), dom.OuterHTML(p))
+		}
+
+		if got := dom.ParentElement(dom.DocumentElement(doc)); got != nil {
+			t.Errorf("ParentElement() = %v, want nil", dom.OuterHTML(got))
+		}
+
+		if got := dom.ParentElement(inFragment); got != nil {
+			t.Errorf("ParentElement() = %v, want nil", dom.OuterHTML(got))
+		}
+	})
+
+	t.Run("root node", func(t *testing.T) {
+		if got := dom.GetRootNode(b); got != doc {
+			t.Errorf("GetRootNode() = %v, want document", dom.OuterHTML(got))
+		}
+
+		if got := dom.GetRootNode(detached.FirstChild); got != detached {
+			t.Errorf("GetRootNode() = %v, want %v", dom.OuterHTML(got), dom.OuterHTML(detached))
+		}
+
+		if got := dom.GetRootNode(detached); got != detached {
+			t.Errorf("GetRootNode() = %v, want %v", dom.OuterHTML(got), dom.OuterHTML(detached))
+		}
+	})
+
+	t.Run("is connected", func(t *testing.T) {
+		tests := []struct {
+			name string
+			node *html.Node
+			want bool
+		}{
+			{"document", doc, true},
+			{"element in document", b, true},
+			{"text in document", text, true},
+			{"detached element", detached, false},
+			{"child of detached element", detached.FirstChild, false},
+			{"element in fragment", inFragment, false},
+		}
+
+		for _, tt := range tests {
+			if got := dom.IsConnected(tt.node); got != tt.want {
+				t.Errorf("IsConnected(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("contains", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			node  *html.Node
+			other *html.Node
+			want  bool
+		}{
+			{"itself", div, div, true},
+			{"direct child", div, p, true},
+			{"deep descendant", div, b.FirstChild, true},
+			{"text sibling", p, text, true},
+			{"ancestor", b, div, false},
+			{"sibling", text, b, false},
+			{"detached", doc, detached, false},
+			{"nil", div, nil, false},
+		}
+
+		for _, tt := range tests {
+			if got := dom.Contains(tt.node, tt.other); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+// TestChildNodeMixin checks Before, After, ReplaceWith and Remove. The cases
+// are adapted from web-platform-tests for ChildNode interface.
+func TestChildNodeMixin(t *testing.T) {
+	type mixinFunc func(node *html.Node, nodes ...interface{})
+
+	type mixinCase struct {
+		name string
+		// args receives the context child and the x, y element
+		args func(child, x, y *html.Node) []interface{}
+		want string
+	}
+
+	runMixinTests := func(t *testing.T, fnName string, fn mixinFunc, tests []mixinCase) {
+		for _, tt := range tests {
+			t.Run(fnName+" "+tt.name, func(t *testing.T) {
+				doc, err := parseHTMLSource(`<div><x></x><c></c><y></y></div>`)
+				if err != nil {
+					t.Errorf("%s(), failed to parse: %v", fnName, err)
+				}
+
+				parent := doc.FirstChild
+				x := dom.QuerySelector(parent, "x")
+				child := dom.QuerySelector(parent, "c")
+				y := dom.QuerySelector(parent, "y")
+
+				fn(child, tt.args(child, x, y)...)
+				if got := dom.InnerHTML(parent); got != tt.want {
+					t.Errorf("%s() = %v, want %v", fnName, got, tt.want)
+				}
+			})
+		}
+	}
+
+	noArgs := func(child, x, y *html.Node) []interface{} { return nil }
+	textOnly := func(child, x, y *html.Node) []interface{} { return []interface{}{"text"} }
+	newElement := func(child, x, y *html.Node) []interface{} {
+		return []interface{}{dom.CreateElement("z")}
+	}
+	mixed := func(child, x, y *html.Node) []interface{} {
+		return []interface{}{dom.CreateElement("z"), "text"}
+	}
+	contextItself := func(child, x, y *html.Node) []interface{} { return []interface{}{child} }
+	contextAndOther := func(child, x, y *html.Node) []interface{} { return []interface{}{"t", child} }
+	allSiblings := func(child, x, y *html.Node) []interface{} { return []interface{}{y, x} }
+	prevSibling := func(child, x, y *html.Node) []interface{} { return []interface{}{x, "t"} }
+	nextSibling := func(child, x, y *html.Node) []interface{} { return []interface{}{"t", y} }
+
+	runMixinTests(t, "Before", dom.Before, []mixinCase{
+		{"with no argument", noArgs, "<x></x><c></c><y></y>"},
+		{"with only text", textOnly, "<x></x>text<c></c><y></y>"},
+		{"with new element", newElement, "<x></x><z></z><c></c><y></y>"},
+		{"with element and text", mixed, "<x></x><z></z>text<c></c><y></y>"},
+		{"with context object itself", contextItself, "<x></x><c></c><y></y>"},
+		{"with context object and text", contextAndOther, "<x></x>t<c></c><y></y>"},
+		{"with all siblings", allSiblings, "<y></y><x></x><c></c>"},
+		{"with previous sibling", prevSibling, "<x></x>t<c></c><y></y>"},
+		{"with next sibling", nextSibling, "<x></x>t<y></y><c></c>"},
+	})
+
+	runMixinTests(t, "After", dom.After, []mixinCase{
+		{"with no argument", noArgs, "<x></x><c></c><y></y>"},
+		{"with only text", textOnly, "<x></x><c></c>text<y></y>"},
+		{"with new element", newElement, "<x></x><c></c><z></z><y></y>"},
+		{"with element and text", mixed, "<x></x><c></c><z></z>text<y></y>"},
+		{"with context object itself", contextItself, "<x></x><c></c><y></y>"},
+		{"with context object and text", contextAndOther, "<x></x>t<c></c><y></y>"},
+		{"with all siblings", allSiblings, "<c></c><y></y><x></x>"},
+		{"with previous sibling", prevSibling, "<c></c><x></x>t<y></y>"},
+		{"with next sibling", nextSibling, "<x></x><c></c>t<y></y>"},
+	})
+
+	runMixinTests(t, "ReplaceWith", dom.ReplaceWith, []mixinCase{
+		{"with no argument", noArgs, "<x></x><y></y>"},
+		{"with only text", textOnly, "<x></x>text<y></y>"},
+		{"with new element", newElement, "<x></x><z></z><y></y>"},
+		{"with element and text", mixed, "<x></x><z></z>text<y></y>"},
+		{"with context object itself", contextItself, "<x></x><c></c><y></y>"},
+		{"with context object and text", contextAndOther, "<x></x>t<c></c><y></y>"},
+		{"with all siblings", allSiblings, "<y></y><x></x>"},
+		{"with previous sibling", prevSibling, "<x></x>t<y></y>"},
+		{"with next sibling", nextSibling, "<x></x>t<y></y>"},
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		doc, err := parseHTMLSource(`<div><x></x><c></c><y></y></div>`)
+		if err != nil {
+			t.Errorf("Remove(), failed to parse: %v", err)
+		}
+
+		parent := doc.FirstChild
+		child := dom.QuerySelector(parent, "c")
+		dom.Remove(child)
+		if got := dom.InnerHTML(parent); got != "<x></x><y></y>" {
+			t.Errorf("Remove() = %v, want %v", got, "<x></x><y></y>")
+		}
+
+		// Removing detached node is no-op
+		dom.Remove(child)
+		if child.Parent != nil {
+			t.Errorf("Remove() parent = %v, want nil", child.Parent)
+		}
+	})
+
+	t.Run("with ancestor", func(t *testing.T) {
+		htmlSource := `<section><div><x></x><c></c><y></y></div></section>`
+		doc, err := parseHTMLSource(htmlSource)
+		if err != nil {
+			t.Errorf("Before(), failed to parse: %v", err)
+		}
+
+		section := doc.FirstChild
+		div := section.FirstChild
+		child := dom.QuerySelector(div, "c")
+		x := dom.QuerySelector(div, "x")
+
+		dom.Before(child, div)
+		dom.After(child, x, section)
+		dom.ReplaceWith(child, "text", div)
+		dom.AppendChild(child, section)
+		dom.ReplaceChild(div, section, child)
+
+		if got := dom.OuterHTML(section); got != htmlSource {
+			t.Errorf("mixin with ancestor = %v, want %v", got, htmlSource)
+		}
+	})
+
+	t.Run("without parent", func(t *testing.T) {
+		child := dom.CreateElement("c")
+		dom.Before(child, "text")
+		dom.After(child, "text")
+		dom.ReplaceWith(child, "text")
+		if child.PrevSibling != nil || child.NextSibling != nil || child.Parent != nil {
+			t.Errorf("mixin on detached node must be no-op")
+		}
+	})
+}
+
+// TestParentNodeMixin checks Append and Prepend. The cases are adapted
+// from web-platform-tests for ParentNode interface.
+func TestParentNodeMixin(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        func(existing *html.Node) []interface{}
+		wantAppend  string
+		wantPrepend string
+	}{{
+		name:        "with no argument",
+		args:        func(existing *html.Node) []interface{} { return nil },
+		wantAppend:  "<a></a>",
+		wantPrepend: "<a></a>",
+	}, {
+		name:        "with only text",
+		args:        func(existing *html.Node) []interface{} { return []interface{}{"text"} },
+		wantAppend:  "<a></a>text",
+		wantPrepend: "text<a></a>",
+	}, {
+		name: "with element and text",
+		args: func(existing *html.Node) []interface{} {
+			return []interface{}{dom.CreateElement("x"), "text"}
+		},
+		wantAppend:  "<a></a><x></x>text",
+		wantPrepend: "<x></x>text<a></a>",
+	}, {
+		name: "with existing child",
+		args: func(existing *html.Node) []interface{} {
+			return []interface{}{"text", existing}
+		},
+		wantAppend:  "text<a></a>",
+		wantPrepend: "text<a></a>",
+	}, {
+		name: "with nil and unsupported value",
+		args: func(existing *html.Node) []interface{} {
+			var nilNode *html.Node
+			return []interface{}{nilNode, 42, "text"}
+		},
+		wantAppend:  "<a></a>text",
+		wantPrepend: "text<a></a>",
+	}}
+
+	for _, tt := range tests {
+		for _, mode := range []string{"Append", "Prepend"} {
+			t.Run(mode+" "+tt.name, func(t *testing.T) {
+				doc, err := parseHTMLSource(`<div><a></a></div>`)
+				if err != nil {
+					t.Errorf("%s(), failed to parse: %v", mode, err)
+				}
+
+				div := doc.FirstChild
+				args := tt.args(div.FirstChild)
+
+				want := tt.wantAppend
+				if mode == "Append" {
+					dom.Append(div, args...)
+				} else {
+					want = tt.wantPrepend
+					dom.Prepend(div, args...)
+				}
+
+				if got := dom.InnerHTML(div); got != want {
+					t.Errorf("%s() = %v, want %v", mode, got, want)
+				}
+			})
+		}
+	}
+
+	t.Run("with ancestor", func(t *testing.T) {
+		htmlSource := `<section><div><a></a></div></section>`
+		doc, err := parseHTMLSource(htmlSource)
+		if err != nil {
+			t.Errorf("Append(), failed to parse: %v", err)
+		}
+
+		section := doc.FirstChild
+		div := section.FirstChild
+		a := div.FirstChild
+
+		// Inserting a node into itself or its descendant is rejected,
+		// without moving any of the other nodes
+		other := dom.CreateElement("x")
+		dom.Append(a, div)
+		dom.Append(div, "text", div)
+		dom.Prepend(a, other, section)
+		dom.Prepend(div, a, div)
+
+		if got := dom.OuterHTML(section); got != htmlSource {
+			t.Errorf("Append() with ancestor = %v, want %v", got, htmlSource)
+		}
+
+		if other.Parent != nil {
+			t.Errorf("Prepend() with ancestor moves the other node")
+		}
+	})
+
+	t.Run("parent is void", func(t *testing.T) {
+		br := dom.CreateElement("br")
+		dom.Append(br, "text")
+		dom.Prepend(br, dom.CreateElement("span"))
+
+		want := "<br/>"
+		if got := dom.OuterHTML(br); got != want {
+			t.Errorf("Append() = %v, want %v", got, want)
+		}
+	})
+}
+
 func TestPreviousElementSibling(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -976,6 +1798,28 @@ func TestPrependChild(t *testing.T) {
 			t.Errorf("PrependChild() = %v, want %v", got, want)
 		}
 	})
+
+	// Child is already the first child
+	t.Run("child is first child", func(t *testing.T) {
+		htmlSource := `<div><p>a</p><p>b</p></div>`
+		want := `<div><p>a</p><p>b</p></div>`
+
+		doc, err := parseHTMLSource(htmlSource)
+		if err != nil {
+			t.Errorf("PrependChild(), failed to parse: %v", err)
+		}
+
+		div := doc.FirstChild
+		first := div.FirstChild
+		dom.PrependChild(div, first)
+		if first.NextSibling == first || first.PrevSibling != nil {
+			t.Fatalf("PrependChild() makes the child its own sibling")
+		}
+
+		if got := dom.OuterHTML(div); got != want {
+			t.Errorf("PrependChild() = %v, want %v", got, want)
+		}
+	})
 }
 
 func TestReplaceChild(t *testing.T) {
@@ -0,0 +1,36 @@
+package dom
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// RenderEncoded renders the node and its descendants like html.Render, but
+// encodes the output using the specified character encoding, e.g. "shift_jis"
+// or "windows-1252". Characters that can't be encoded are written as numeric
+// character reference. Note that character reference is not decoded inside
+// raw text elements like <script> and <style>. The encoding that declared by
+// the document is not changed, so use Document.SetMetaCharset beforehand to
+// make it match the output.
+func RenderEncoded(w io.Writer, node *html.Node, charsetName string) error {
+	if node == nil {
+		return nil
+	}
+
+	enc, _ := charset.Lookup(charsetName)
+	if enc == nil {
+		return fmt.Errorf("dom: unknown charset %q", charsetName)
+	}
+
+	tw := transform.NewWriter(w, encoding.HTMLEscapeUnsupported(enc.NewEncoder()))
+	if err := html.Render(tw, node); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,68 @@
+package dom_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+func TestRenderEncoded(t *testing.T) {
+	htmlSource := `<p title="日本語 😀">日本語のページ 한국어 &amp; ©</p>`
+
+	body, err := parseHTMLSource(htmlSource)
+	if err != nil {
+		t.Fatalf("RenderEncoded(), failed to parse: %v", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := dom.RenderEncoded(&buffer, body.FirstChild, "shift_jis"); err != nil {
+		t.Fatalf("RenderEncoded() error = %v", err)
+	}
+
+	decoded, _, err := transform.String(japanese.ShiftJIS.NewDecoder(), buffer.String())
+	if err != nil {
+		t.Fatalf("RenderEncoded(), failed to decode: %v", err)
+	}
+
+	want := `<p title="日本語 &#128512;">日本語のページ &#54620;&#44397;&#50612; &amp; &#169;</p>`
+	if decoded != want {
+		t.Errorf("RenderEncoded() = %q, want %q", decoded, want)
+	}
+
+	// Character references are decoded back by the parser
+	reparsed, err := parseHTMLSource(decoded)
+	if err != nil {
+		t.Fatalf("RenderEncoded(), failed to reparse: %v", err)
+	}
+
+	if got, want := dom.OuterHTML(reparsed.FirstChild), dom.OuterHTML(body.FirstChild); got != want {
+		t.Errorf("RenderEncoded() reparsed = %q, want %q", got, want)
+	}
+
+	if err := dom.RenderEncoded(&buffer, body, "unknown-charset"); err == nil {
+		t.Errorf("RenderEncoded() error = nil, want error for unknown charset")
+	}
+}
+
+func TestRenderEncodedDocument(t *testing.T) {
+	doc, err := dom.FastParseDocument(strings.NewReader(`<meta charset="utf-8"><p>café</p>`))
+	if err != nil {
+		t.Fatalf("RenderEncoded(), failed to parse: %v", err)
+	}
+
+	doc.SetMetaCharset("windows-1252")
+
+	var buffer bytes.Buffer
+	if err := dom.RenderEncoded(&buffer, doc.Node, "windows-1252"); err != nil {
+		t.Fatalf("RenderEncoded() error = %v", err)
+	}
+
+	want := "<html><head><meta charset=\"windows-1252\"/></head><body><p>caf\xe9</p></body></html>"
+	if got := buffer.String(); got != want {
+		t.Errorf("RenderEncoded() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNodesNotEqual is returned by CompareNodes when the nodes are not equal.
+var ErrNodesNotEqual = errors.New("dom: nodes are not equal")
+
+// EqualOptions is the options for comparing nodes. The zero value
+// compares the nodes following the DOM equality, like IsEqualNode.
+type EqualOptions struct {
+	// IgnoreWhitespace skips the text nodes that only contain ASCII whitespace.
+	IgnoreWhitespace bool
+
+	// IgnoreComments skips the comment nodes.
+	IgnoreComments bool
+
+	// IgnoreAttributes is the list of attribute names that not compared,
+	// e.g. "style". For namespaced attribute, the name must be qualified
+	// with its prefix, e.g. "xlink:href".
+	IgnoreAttributes []string
+}
+
+// IsSameNode check whether both a and b are the same node.
+func IsSameNode(a, b *html.Node) bool {
+	return a == b
+}
+
+// IsEqualNode check whether a and b are equal following the DOM equality, i.e.
+// both of them have the same type, name, namespace, data and attributes (in
+// any order), and their child nodes are equal as well.
+func IsEqualNode(a, b *html.Node) bool {
+	return CompareNodes(a, b, EqualOptions{}) == nil
+}
+
+// IsEqualNodeWithOptions is like IsEqualNode, but
+// compares the nodes following the specified options.
+func IsEqualNodeWithOptions(a, b *html.Node, opts EqualOptions) bool {
+	return CompareNodes(a, b, opts) == nil
+}
+
+// CompareNodes compares a and b like IsEqualNodeWithOptions, and returns an
+// error that describes the first mismatch if they are not equal, or nil if
+// they are equal. The error mentions the path of the mismatched node in a,
+// so it's useful for test failures where a is the result and b is the
+// expected tree. The returned error wraps ErrNodesNotEqual.
+func CompareNodes(a, b *html.Node, opts EqualOptions) error {
+	c := nodeComparer{
+		opts:             opts,
+		ignoreAttributes: make(map[string]struct{}, len(opts.IgnoreAttributes)),
+	}
+
+	for _, name := range opts.IgnoreAttributes {
+		c.ignoreAttributes[name] = struct{}{}
+	}
+
+	return c.compare(a, b, []int{})
+}
+
+type nodeComparer struct {
+	opts             EqualOptions
+	ignoreAttributes map[string]struct{}
+}
+
+func (c *nodeComparer) compare(a, b *html.Node, path []int) error {
+	mismatch := func(format string, args ...interface{}) error {
+		location := formatPath(path)
+		if a != nil {
+			location += " (" + nodeLabel(a) + ")"
+		}
+		return fmt.Errorf("%w: at %s: %s", ErrNodesNotEqual, location, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		return mismatch("node is nil, want %s", nodeName(b))
+	case b == nil:
+		return mismatch("node is %s, want nil", nodeName(a))
+	case a.Type != b.Type || a.Namespace != b.Namespace ||
+		(a.Type == html.ElementNode && a.Data != b.Data):
+		return mismatch("node is %s, want %s", nodeName(a), nodeName(b))
+	case a.Type != html.ElementNode && a.Data != b.Data:
+		return mismatch("data is %q, want %q", a.Data, b.Data)
+	}
+
+	if err := c.compareAttributes(a, b); err != "" {
+		return mismatch("%s", err)
+	}
+
+	// Compare the child nodes, skipping the ignored ones
+	aChildren, aIndexes := c.childNodes(a)
+	bChildren, _ := c.childNodes(b)
+	for i := 0; i < len(aChildren) && i < len(bChildren); i++ {
+		if err := c.compare(aChildren[i], bChildren[i], childPath(path, aIndexes[i])); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(aChildren) > len(bChildren):
+		return mismatch("has unexpected child %s", nodeName(aChildren[len(bChildren)]))
+	case len(aChildren) < len(bChildren):
+		return mismatch("is missing child %s", nodeName(bChildren[len(aChildren)]))
+	}
+
+	return nil
+}
+
+// compareAttributes compares the attributes of a and b regardless of their
+// order, and returns the description of the first mismatch.
+func (c *nodeComparer) compareAttributes(a, b *html.Node) string {
+	bAttrs := make(map[string]string, len(b.Attr))
+	for _, attr := range b.Attr {
+		if name := attributeQualifiedName(attr); !c.isIgnoredAttribute(name) {
+			bAttrs[name] = attr.Val
+		}
+	}
+
+	nAttrs := 0
+	for _, attr := range a.Attr {
+		name := attributeQualifiedName(attr)
+		if c.isIgnoredAttribute(name) {
+			continue
+		}
+
+		val, exist := bAttrs[name]
+		switch {
+		case !exist:
+			return fmt.Sprintf("has unexpected attribute %s", name)
+		case val != attr.Val:
+			return fmt.Sprintf("attribute %s is %q, want %q", name, attr.Val, val)
+		}
+		nAttrs++
+	}
+
+	if nAttrs != len(bAttrs) {
+		for _, attr := range b.Attr {
+			name := attributeQualifiedName(attr)
+			if !c.isIgnoredAttribute(name) && !hasQualifiedAttribute(a, name) {
+				return fmt.Sprintf("is missing attribute %s", name)
+			}
+		}
+	}
+
+	return ""
+}
+
+func (c *nodeComparer) isIgnoredAttribute(name string) bool {
+	_, ignored := c.ignoreAttributes[name]
+	return ignored
+}
+
+// childNodes returns the child nodes that not ignored, along with their index.
+func (c *nodeComparer) childNodes(node *html.Node) ([]*html.Node, []int) {
+	var children []*html.Node
+	var indexes []int
+
+	idx := 0
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		ignored := (c.opts.IgnoreComments && child.Type == html.CommentNode) ||
+			(c.opts.IgnoreWhitespace && child.Type == html.TextNode && strings.TrimLeft(child.Data, " \t\n\f\r") == "")
+
+		if !ignored {
+			children = append(children, child)
+			indexes = append(indexes, idx)
+		}
+		idx++
+	}
+
+	return children, indexes
+}
+
+func hasQualifiedAttribute(node *html.Node, name string) bool {
+	for _, attr := range node.Attr {
+		if attributeQualifiedName(attr) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeName returns the name of node that used in the mismatch description,
+// e.g. "<p>" for element, or "#text" for text node.
+func nodeName(node *html.Node) string {
+	if node.Type != html.ElementNode {
+		return nodeLabel(node)
+	}
+
+	if node.Namespace != "" {
+		return "<" + node.Namespace + ":" + node.Data + ">"
+	}
+	return "<" + node.Data + ">"
+}
@@ -0,0 +1,176 @@
+package dom_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestIsSameNode(t *testing.T) {
+	a, b := dom.CreateElement("p"), dom.CreateElement("p")
+	if !dom.IsSameNode(a, a) {
+		t.Errorf("IsSameNode(a, a) = false, want true")
+	}
+
+	if dom.IsSameNode(a, b) {
+		t.Errorf("IsSameNode(a, b) = true, want false")
+	}
+}
+
+func TestCompareNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		opts dom.EqualOptions
+		want string
+	}{{
+		name: "equal",
+		a:    `<div id="a" class="x"><p>text</p><!--c--></div>`,
+		b:    `<div id="a" class="x"><p>text</p><!--c--></div>`,
+	}, {
+		name: "attributes in different order",
+		a:    `<a href="/x" title="t">link</a>`,
+		b:    `<a title="t" href="/x">link</a>`,
+	}, {
+		name: "different tag",
+		a:    `<div><p>x</p></div>`,
+		b:    `<div><span>x</span></div>`,
+		want: "at /0/1/0/0 (p): node is <p>, want <span>",
+	}, {
+		name: "different text",
+		a:    `<p>hello</p>`,
+		b:    `<p>world</p>`,
+		want: `at /0/1/0/0 (#text): data is "hello", want "world"`,
+	}, {
+		name: "different attribute value",
+		a:    `<p class="a">x</p>`,
+		b:    `<p class="b">x</p>`,
+		want: `at /0/1/0 (p.a): attribute class is "a", want "b"`,
+	}, {
+		name: "unexpected attribute",
+		a:    `<p id="x" title="y">x</p>`,
+		b:    `<p id="x">x</p>`,
+		want: `at /0/1/0 (p#x): has unexpected attribute title`,
+	}, {
+		name: "missing attribute",
+		a:    `<svg><a>x</a></svg>`,
+		b:    `<svg><a xlink:href="#y">x</a></svg>`,
+		want: `at /0/1/0/0 (svg:a): is missing attribute xlink:href`,
+	}, {
+		name: "unexpected child",
+		a:    `<ul><li>1</li><li>2</li></ul>`,
+		b:    `<ul><li>1</li></ul>`,
+		want: `at /0/1/0 (ul): has unexpected child <li>`,
+	}, {
+		name: "missing child",
+		a:    `<p>x</p>`,
+		b:    `<p>x<!--c--></p>`,
+		want: `at /0/1/0 (p): is missing child #comment`,
+	}, {
+		name: "whitespace",
+		a:    "<ul>\n  <li>1</li>\n  <li>2</li>\n</ul>",
+		b:    `<ul><li>1</li><li>2</li></ul>`,
+		want: `at /0/1/0/0 (#text): node is #text, want <li>`,
+	}, {
+		name: "ignore whitespace",
+		a:    "<ul>\n  <li>1</li>\n  <li>2</li>\n</ul>",
+		b:    `<ul><li>1</li><li>2</li></ul>`,
+		opts: dom.EqualOptions{IgnoreWhitespace: true},
+	}, {
+		name: "ignore whitespace keeps the path",
+		a:    "<ul>\n  <li>1</li>\n  <li>2</li>\n</ul>",
+		b:    `<ul><li>1</li><li>3</li></ul>`,
+		opts: dom.EqualOptions{IgnoreWhitespace: true},
+		want: `at /0/1/0/3/0 (#text): data is "2", want "3"`,
+	}, {
+		name: "ignore comments",
+		a:    `<p>x<!--a--></p><!--b-->`,
+		b:    `<p><!--c-->x</p>`,
+		opts: dom.EqualOptions{IgnoreComments: true},
+	}, {
+		name: "ignore attributes",
+		a:    `<p style="color:red" data-id="1">x</p>`,
+		b:    `<p data-id="1">x</p>`,
+		opts: dom.EqualOptions{IgnoreAttributes: []string{"style"}},
+	}, {
+		name: "doctype",
+		a:    `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01//EN">`,
+		b:    `<!DOCTYPE html>`,
+		want: `at /0 (#doctype): has unexpected attribute public`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := dom.FastParse(strings.NewReader(tt.a))
+			if err != nil {
+				t.Fatalf("CompareNodes(), failed to parse: %v", err)
+			}
+
+			b, err := dom.FastParse(strings.NewReader(tt.b))
+			if err != nil {
+				t.Fatalf("CompareNodes(), failed to parse: %v", err)
+			}
+
+			err = dom.CompareNodes(a, b, tt.opts)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("CompareNodes() error = %v, want nil", err)
+				}
+			} else if err == nil || !errors.Is(err, dom.ErrNodesNotEqual) || err.Error() != "dom: nodes are not equal: "+tt.want {
+				t.Errorf("CompareNodes() error = %v, want %q", err, tt.want)
+			}
+
+			if got := dom.IsEqualNodeWithOptions(a, b, tt.opts); got != (tt.want == "") {
+				t.Errorf("IsEqualNodeWithOptions() = %v, want %v", got, tt.want == "")
+			}
+		})
+	}
+}
+
+func TestIsEqualNode(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *html.Node
+		b    *html.Node
+		want bool
+	}{{
+		name: "both nil",
+		want: true,
+	}, {
+		name: "one nil",
+		a:    dom.CreateElement("p"),
+		want: false,
+	}, {
+		name: "same element",
+		a:    dom.CreateElement("p"),
+		b:    dom.CreateElement("p"),
+		want: true,
+	}, {
+		name: "different namespace",
+		a:    dom.CreateElement("a"),
+		b:    dom.CreateElementNS(dom.SVGNamespace, "a"),
+		want: false,
+	}, {
+		name: "text and comment",
+		a:    dom.CreateTextNode("x"),
+		b:    dom.CreateComment("x"),
+		want: false,
+	}, {
+		name: "document and fragment",
+		a:    dom.CreateHTMLDocument(""),
+		b:    dom.CreateDocumentFragment(),
+		want: false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dom.IsEqualNode(tt.a, tt.b); got != tt.want {
+				t.Errorf("IsEqualNode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package dom
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/width"
+)
+
+// textItem is an item that produced by the rendered text collection steps.
+// It's either a text, or a required line break count.
+type textItem struct {
+	text       string
+	whiteSpace string
+	breakCount int
+}
+
+// renderedText implements the algorithm for `innerText` getter that described
+// in HTML specification, using cascade to compute the style of the nodes.
+func renderedText(node *html.Node, cascade *Cascade, opts TextOptions) string {
+	if !isBeingRendered(node, cascade) {
+		return TextContent(node)
+	}
+
+	var items []textItem
+	if node.Type == html.TextNode {
+		items = collectRenderedText(node, cascade, opts)
+	} else {
+		items = collectChildrenText(node, cascade, opts)
+	}
+
+	return joinTextItems(items)
+}
+
+// collectRenderedText implements the rendered text collection steps.
+func collectRenderedText(node *html.Node, cascade *Cascade, opts TextOptions) []textItem {
+	style := cascade.ComputedStyle(node)
+	if node.Type == html.ElementNode && (style.Display == "none" || isExcludedTag(node, opts)) {
+		return nil
+	}
+
+	items := collectChildrenText(node, cascade, opts)
+
+	if style.Visibility != "visible" {
+		return items
+	}
+
+	switch node.Type {
+	case html.TextNode:
+		return append(items, textItem{text: node.Data, whiteSpace: style.WhiteSpace})
+	case html.ElementNode:
+	default:
+		return items
+	}
+
+	if node.Data == "br" && node.Namespace == "" {
+		items = append(items, textItem{text: "\n", whiteSpace: "pre"})
+	}
+
+	switch style.Display {
+	case "table-cell":
+		if !isLastTableCell(node, cascade) {
+			items = append(items, textItem{text: "\t", whiteSpace: "pre"})
+		}
+	case "table-row":
+		if !isLastTableRow(node, cascade) {
+			items = append(items, textItem{text: "\n", whiteSpace: "pre"})
+		}
+	}
+
+	breakCount := 0
+	switch {
+	case node.Data == "p" && node.Namespace == "":
+		breakCount = 2
+	case isBlockLevelDisplay(style.Display):
+		breakCount = 1
+	}
+
+	if breakCount > 0 {
+		items = append([]textItem{{breakCount: breakCount}}, items...)
+		items = append(items, textItem{breakCount: breakCount})
+	}
+
+	return items
+}
+
+// collectChildrenText runs the rendered text collection steps for
+// each child of the node. Children of ruby element are arranged
+// following the ruby mode in options.
+func collectChildrenText(node *html.Node, cascade *Cascade, opts TextOptions) []textItem {
+	var items []textItem
+	if opts.Ruby == RubyKeep || !isHTMLElement(node, "ruby") {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			items = append(items, collectRenderedText(child, cascade, opts)...)
+		}
+		return items
+	}
+
+	collectNodes := func(nodes []*html.Node) []textItem {
+		var nodeItems []textItem
+		for _, n := range nodes {
+			nodeItems = append(nodeItems, collectRenderedText(n, cascade, opts)...)
+		}
+		return nodeItems
+	}
+
+	for _, segment := range rubySegments(node) {
+		base := collectNodes(segment.base)
+		annotation := collectNodes(segment.annotation)
+
+		switch {
+		case !hasTextItem(annotation) || opts.Ruby == RubyBaseOnly:
+			items = append(items, base...)
+		case opts.Ruby == RubyAnnotationOnly:
+			items = append(items, annotation...)
+		default:
+			items = append(items, base...)
+			items = append(items, textItem{text: "(", whiteSpace: "pre"})
+			items = append(items, annotation...)
+			items = append(items, textItem{text: ")", whiteSpace: "pre"})
+		}
+	}
+
+	return items
+}
+
+// hasTextItem check whether there are any non-whitespace text in the items.
+func hasTextItem(items []textItem) bool {
+	for _, item := range items {
+		if strings.TrimSpace(item.text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// joinTextItems joins the collected text items while applying the white space
+// processing of CSS, and converting the required line break counts into
+// newlines. Leading and trailing required line breaks are removed.
+func joinTextItems(items []textItem) string {
+	j := textJoiner{lineStart: true}
+
+	for _, item := range items {
+		if item.breakCount > 0 {
+			if item.breakCount > j.pendingBreaks {
+				j.pendingBreaks = item.breakCount
+			}
+			j.clearPendingSpace()
+			j.lineStart = true
+			continue
+		}
+
+		switch item.whiteSpace {
+		case "pre", "pre-wrap", "break-spaces":
+			// Line break and tab in preserved text ends the current line, so
+			// the collapsible space before them must be removed
+			if strings.HasPrefix(item.text, "\n") || strings.HasPrefix(item.text, "\t") {
+				j.clearPendingSpace()
+			}
+
+			if item.text != "" {
+				j.writeContent(item.text)
+			}
+
+		default:
+			preserveNewline := item.whiteSpace == "pre-line"
+			for _, char := range item.text {
+				switch {
+				case char == '\n' && preserveNewline:
+					j.clearPendingSpace()
+					j.writeContent("\n")
+				case char == '\n':
+					j.pendingSpace = true
+					j.pendingSegmentBreak = true
+				case char == ' ' || char == '\t' || char == '\r':
+					j.pendingSpace = true
+				default:
+					j.writeContent(string(char))
+				}
+			}
+		}
+	}
+
+	return j.sb.String()
+}
+
+// textJoiner is the state for joining text items.
+type textJoiner struct {
+	sb strings.Builder
+
+	// pendingBreaks is the required line break count that
+	// will be written before the next content.
+	pendingBreaks int
+
+	// pendingSpace marks there is a collapsible space before the next
+	// content, and pendingSegmentBreak marks that space contains a
+	// segment break (i.e. newline in source).
+	pendingSpace        bool
+	pendingSegmentBreak bool
+
+	// lineStart marks the next content is the start of a line,
+	// so the collapsible space before it is removed.
+	lineStart bool
+
+	// lastRune is the last written rune, excluding format character
+	// other than zero width space.
+	lastRune rune
+}
+
+// writeContent writes a non-collapsible content, preceded by the
+// pending line breaks or the pending collapsible space.
+func (j *textJoiner) writeContent(content string) {
+	first, _ := utf8.DecodeRuneInString(content)
+
+	// Format character like bidi mark is transparent for the space
+	// processing, so the pending space is kept for the next content
+	if j.pendingBreaks == 0 && j.pendingSpace && unicode.Is(unicode.Cf, first) && len(content) == utf8.RuneLen(first) {
+		if first == zeroWidthSpace && j.pendingSegmentBreak {
+			j.clearPendingSpace()
+		}
+		j.sb.WriteString(content)
+		return
+	}
+
+	switch {
+	case j.pendingBreaks > 0 && j.sb.Len() > 0:
+		j.sb.WriteString(strings.Repeat("\n", j.pendingBreaks))
+	case j.pendingSpace && !j.lineStart && keepCollapsibleSpace(j.lastRune, first, j.pendingSegmentBreak):
+		j.sb.WriteByte(' ')
+	}
+
+	j.sb.WriteString(content)
+	j.pendingBreaks = 0
+	j.clearPendingSpace()
+	j.lineStart = strings.HasSuffix(content, "\n") || strings.HasSuffix(content, "\t")
+
+	for i := len(content); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(content[:i])
+		if r == zeroWidthSpace || !unicode.Is(unicode.Cf, r) {
+			j.lastRune = r
+			break
+		}
+		i -= size
+	}
+}
+
+func (j *textJoiner) clearPendingSpace() {
+	j.pendingSpace = false
+	j.pendingSegmentBreak = false
+}
+
+// isBeingRendered check whether the node is being rendered, i.e. neither
+// the node nor its ancestors have display none.
+func isBeingRendered(node *html.Node, cascade *Cascade) bool {
+	for n := node; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && cascade.ComputedStyle(n).Display == "none" {
+			return false
+		}
+	}
+	return true
+}
+
+// isBlockLevelDisplay check whether the display value generates
+// a block-level box.
+func isBlockLevelDisplay(display string) bool {
+	switch display {
+	case "block", "list-item", "table", "flex", "grid", "flow-root",
+		"table-caption", "ruby-base-container":
+		return true
+	default:
+		return false
+	}
+}
+
+// isLastTableCell check whether there are no more rendered
+// table cells after the node in its row.
+func isLastTableCell(node *html.Node, cascade *Cascade) bool {
+	for sibling := node.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type == html.ElementNode && cascade.ComputedStyle(sibling).Display == "table-cell" {
+			return false
+		}
+	}
+	return true
+}
+
+// isLastTableRow check whether there are no more rendered table
+// rows after the node in its nearest ancestor table.
+func isLastTableRow(node *html.Node, cascade *Cascade) bool {
+	var table *html.Node
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type == html.ElementNode {
+			display := cascade.ComputedStyle(parent).Display
+			if display == "table" || display == "inline-table" {
+				table = parent
+				break
+			}
+		}
+	}
+
+	if table == nil {
+		return true
+	}
+
+	// Find the last row in the table, without entering nested table
+	var lastRow *html.Node
+	var finder func(*html.Node)
+	finder = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+
+			switch cascade.ComputedStyle(child).Display {
+			case "table-row":
+				lastRow = child
+			case "table-row-group", "table-header-group", "table-footer-group":
+				finder(child)
+			}
+		}
+	}
+
+	finder(table)
+	return lastRow == node
+}
+
+const zeroWidthSpace = '\u200B'
+
+// keepCollapsibleSpace decides whether a collapsible space between two
+// runes is rendered. Segment break between East Asian wide characters is
+// removed as described in CSS Text, since those scripts don't use space to
+// separate words. The space around fullwidth punctuation and the space before
+// closing punctuation (including the one used in RTL scripts) is removed as
+// well, since it's usually the artifact of the HTML formatting.
+func keepCollapsibleSpace(before, after rune, segmentBreak bool) bool {
+	if segmentBreak {
+		if before == zeroWidthSpace || after == zeroWidthSpace {
+			return false
+		}
+
+		if isEastAsianWide(before) && isEastAsianWide(after) {
+			return false
+		}
+	}
+
+	if isWidePunctuation(before) || isWidePunctuation(after) {
+		return false
+	}
+
+	return !isClosingPunctuation(after)
+}
+
+// isEastAsianWide check whether the rune has East Asian Width property of
+// fullwidth, wide or halfwidth, excluding Hangul which uses space between words.
+func isEastAsianWide(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianFullwidth, width.EastAsianWide, width.EastAsianHalfwidth:
+		return !unicode.Is(unicode.Hangul, r)
+	default:
+		return false
+	}
+}
+
+// isWidePunctuation check whether the rune is a fullwidth punctuation,
+// e.g. ideographic full stop and comma, or corner brackets.
+func isWidePunctuation(r rune) bool {
+	return unicode.IsPunct(r) && isEastAsianWide(r)
+}
+
+// isClosingPunctuation check whether the rune is a punctuation which
+// shouldn't be preceded by space, including the one used in RTL scripts.
+func isClosingPunctuation(r rune) bool {
+	switch r {
+	case '.', '?', '!', ',', ';',
+		'\u060C', // Arabic comma
+		'\u061B', // Arabic semicolon
+		'\u061F', // Arabic question mark
+		'\u06D4', // Arabic full stop
+		'\u05C3': // Hebrew punctuation sof pasuq
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,663 @@
+package dom
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	rxMarkdownEntity    = regexp.MustCompile(`&(#?[A-Za-z0-9]+;)`)
+	rxMarkdownLineStart = regexp.MustCompile(`^([#>+=-]|\d+[.)])(\s|$)`)
+)
+
+// MarkdownOptions is the options for converting HTML into Markdown.
+type MarkdownOptions struct {
+	// BaseURL is the URL that used to resolve the relative URL in links
+	// and images. If it's empty, the URL is kept as it is.
+	BaseURL string
+}
+
+// ToMarkdown converts the node and its descendants into GitHub Flavored Markdown.
+// It handles headings, paragraphs, emphasis, links, images, nested lists, block
+// quotes, code blocks (with language from `class="language-x"`) and tables.
+// Elements that can't be represented in Markdown (e.g. <sup>, <dl> or <video>)
+// are kept as inline HTML, while <script>, <style> and <head> are removed.
+func ToMarkdown(node *html.Node, opts MarkdownOptions) string {
+	if node == nil {
+		return ""
+	}
+
+	r := markdownRenderer{}
+	if opts.BaseURL != "" {
+		if baseURL, err := url.Parse(opts.BaseURL); err == nil {
+			r.baseURL = baseURL
+		}
+	}
+
+	blocks := r.renderNodes([]*html.Node{node})
+	if len(blocks) == 0 {
+		return ""
+	}
+
+	return joinMarkdownBlocks(blocks, "\n\n") + "\n"
+}
+
+// markdownBlock is a rendered block in Markdown document.
+type markdownBlock struct {
+	text   string
+	isList bool
+}
+
+// markdownInline is the buffer for rendering inline content. Like in HTML,
+// whitespace is collapsed, so the space is kept pending until the next
+// content is written.
+type markdownInline struct {
+	sb           strings.Builder
+	inTable      bool
+	leadingSpace bool
+	pendingSpace bool
+}
+
+func (w *markdownInline) writeText(text string) {
+	fields := strings.FieldsFunc(text, isASCIIWhitespace)
+	if len(fields) == 0 {
+		if text != "" {
+			w.addSpace()
+		}
+		return
+	}
+
+	if isASCIIWhitespace(rune(text[0])) {
+		w.addSpace()
+	}
+
+	for i, field := range fields {
+		if i > 0 {
+			w.addSpace()
+		}
+		w.writeRaw(escapeMarkdown(field))
+	}
+
+	if isASCIIWhitespace(rune(text[len(text)-1])) {
+		w.addSpace()
+	}
+}
+
+// writeRaw writes the string as it is, preceded by the pending space.
+func (w *markdownInline) writeRaw(str string) {
+	if w.pendingSpace && w.sb.Len() > 0 && !strings.HasSuffix(w.sb.String(), "\n") {
+		w.sb.WriteByte(' ')
+	}
+
+	w.sb.WriteString(str)
+	w.pendingSpace = false
+}
+
+func (w *markdownInline) addSpace() {
+	if w.sb.Len() == 0 {
+		w.leadingSpace = true
+	}
+	w.pendingSpace = true
+}
+
+// writeWrapped writes the content of other inline buffer wrapped by the
+// prefix and suffix. The leading and trailing space of the content are
+// moved outside, since emphasis in Markdown can't start or end with space.
+func (w *markdownInline) writeWrapped(content *markdownInline, prefix, suffix string) {
+	if content.leadingSpace {
+		w.addSpace()
+	}
+
+	if content.sb.Len() > 0 {
+		w.writeRaw(prefix + content.sb.String() + suffix)
+	}
+
+	if content.pendingSpace {
+		w.addSpace()
+	}
+}
+
+type markdownRenderer struct {
+	baseURL *url.URL
+}
+
+// renderNodes renders the nodes into Markdown blocks. Consecutive
+// inline nodes are grouped into a paragraph.
+func (r *markdownRenderer) renderNodes(nodes []*html.Node) []markdownBlock {
+	var blocks []markdownBlock
+	inline := &markdownInline{}
+
+	flushParagraph := func() {
+		if text := markdownParagraph(inline.sb.String()); text != "" {
+			blocks = append(blocks, markdownBlock{text: text})
+		}
+		inline = &markdownInline{}
+	}
+
+	for _, node := range nodes {
+		switch {
+		case node.Type == html.DocumentNode:
+			flushParagraph()
+			blocks = append(blocks, r.renderChildren(node)...)
+		case isMarkdownBlock(node):
+			flushParagraph()
+			blocks = append(blocks, r.renderBlock(node)...)
+		default:
+			r.writeInline(inline, node)
+		}
+	}
+
+	flushParagraph()
+	return blocks
+}
+
+func (r *markdownRenderer) renderChildren(node *html.Node) []markdownBlock {
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, child)
+	}
+	return r.renderNodes(children)
+}
+
+func (r *markdownRenderer) renderBlock(node *html.Node) []markdownBlock {
+	switch node.Data {
+	case "head", "script", "style", "template", "noscript", "title", "meta", "link":
+		return nil
+
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		content := r.renderInlineChildren(node, false)
+		content = strings.ReplaceAll(content, "\\\n", " ")
+		if content == "" {
+			return nil
+		}
+
+		level := int(node.Data[1] - '0')
+		return []markdownBlock{{text: strings.Repeat("#", level) + " " + content}}
+
+	case "p":
+		text := markdownParagraph(r.renderInlineChildren(node, false))
+		if text == "" {
+			return nil
+		}
+		return []markdownBlock{{text: text}}
+
+	case "blockquote":
+		blocks := r.renderChildren(node)
+		if len(blocks) == 0 {
+			return nil
+		}
+
+		lines := strings.Split(joinMarkdownBlocks(blocks, "\n\n"), "\n")
+		for i, line := range lines {
+			if line == "" {
+				lines[i] = ">"
+			} else {
+				lines[i] = "> " + line
+			}
+		}
+		return []markdownBlock{{text: strings.Join(lines, "\n")}}
+
+	case "ul", "ol":
+		text := r.renderList(node)
+		if text == "" {
+			return nil
+		}
+		return []markdownBlock{{text: text, isList: true}}
+
+	case "pre":
+		return []markdownBlock{{text: renderMarkdownCodeBlock(node)}}
+
+	case "hr":
+		return []markdownBlock{{text: "---"}}
+
+	case "table":
+		return r.renderTable(node)
+
+	case "html", "body", "div", "section", "article", "main", "header", "footer",
+		"nav", "aside", "figure", "figcaption", "hgroup", "address", "center":
+		return r.renderChildren(node)
+
+	default:
+		return []markdownBlock{{text: OuterHTML(node)}}
+	}
+}
+
+// renderInlineChildren renders the children of the node as inline content.
+func (r *markdownRenderer) renderInlineChildren(node *html.Node, inTable bool) string {
+	inline := &markdownInline{inTable: inTable}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.writeInline(inline, child)
+	}
+	return strings.TrimSpace(inline.sb.String())
+}
+
+func (r *markdownRenderer) writeInline(w *markdownInline, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		w.writeText(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	// Foreign elements like SVG and MathML can't be represented in Markdown
+	if node.Namespace != "" {
+		w.writeRaw(OuterHTML(node))
+		return
+	}
+
+	writeChildren := func() *markdownInline {
+		content := &markdownInline{inTable: w.inTable}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			r.writeInline(content, child)
+		}
+		return content
+	}
+
+	switch node.Data {
+	case "head", "script", "style", "template", "noscript", "title", "meta", "link":
+		return
+
+	case "br":
+		switch {
+		case w.inTable:
+			w.writeRaw("<br>")
+		case w.sb.Len() > 0:
+			w.sb.WriteString("\\\n")
+			w.pendingSpace = false
+		}
+
+	case "strong", "b":
+		w.writeWrapped(writeChildren(), "**", "**")
+
+	case "em", "i":
+		w.writeWrapped(writeChildren(), "*", "*")
+
+	case "del", "s", "strike":
+		w.writeWrapped(writeChildren(), "~~", "~~")
+
+	case "code", "tt", "samp":
+		w.writeRaw(renderMarkdownCodeSpan(TextContent(node)))
+
+	case "a":
+		content := writeChildren()
+		if !HasAttribute(node, "href") {
+			w.writeWrapped(content, "", "")
+			return
+		}
+
+		destination := formatMarkdownDestination(r.resolveURL(GetAttribute(node, "href")), GetAttribute(node, "title"))
+		w.writeWrapped(content, "[", "]"+destination)
+		if content.sb.Len() == 0 {
+			w.writeRaw("[]" + destination)
+		}
+
+	case "img":
+		src := GetAttribute(node, "src")
+		if src == "" {
+			return
+		}
+
+		alt := strings.Join(strings.Fields(GetAttribute(node, "alt")), " ")
+		destination := formatMarkdownDestination(r.resolveURL(src), GetAttribute(node, "title"))
+		w.writeRaw("![" + escapeMarkdown(alt) + "]" + destination)
+
+	case "span", "font", "small", "big", "abbr", "cite", "time", "label",
+		"bdi", "bdo", "data", "dfn", "var", "picture":
+		w.writeWrapped(writeChildren(), "", "")
+
+	default:
+		// Block elements inside inline context (e.g. <p> inside table cell) are
+		// separated by space, while the other elements are kept as HTML
+		if isMarkdownBlock(node) {
+			w.addSpace()
+			w.writeWrapped(writeChildren(), "", "")
+			w.addSpace()
+			return
+		}
+
+		w.writeRaw(OuterHTML(node))
+	}
+}
+
+// renderList renders <ul> and <ol> into Markdown list. The content of each
+// item is indented to the width of its marker, so nested blocks and lists
+// stay inside the item.
+func (r *markdownRenderer) renderList(list *html.Node) string {
+	number := 1
+	if list.Data == "ol" {
+		if start, err := strconv.Atoi(GetAttribute(list, "start")); err == nil {
+			number = start
+		}
+	}
+
+	var items [][]markdownBlock
+	for child := list.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode && strings.TrimSpace(child.Data) == "":
+			continue
+		case isHTMLElement(child, "li"):
+			items = append(items, r.renderChildren(child))
+		case (isHTMLElement(child, "ul") || isHTMLElement(child, "ol")) && len(items) > 0:
+			// Nested list that put directly inside list is
+			// treated as the part of the previous item
+			last := len(items) - 1
+			items[last] = append(items[last], r.renderBlock(child)...)
+		default:
+			items = append(items, r.renderNodes([]*html.Node{child}))
+		}
+	}
+
+	loose := false
+	renderedItems := make([]string, len(items))
+	for i, blocks := range items {
+		marker := "- "
+		if list.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", number+i)
+		}
+
+		// Paragraph that followed by nested list is kept tight,
+		// while multiple paragraphs make the list loose
+		separator := "\n"
+		for j := 1; j < len(blocks); j++ {
+			if !blocks[j].isList {
+				separator = "\n\n"
+				loose = true
+			}
+		}
+
+		lines := strings.Split(joinMarkdownBlocks(blocks, separator), "\n")
+		indent := strings.Repeat(" ", len(marker))
+		for j := range lines {
+			switch {
+			case j == 0:
+				lines[j] = strings.TrimRight(marker+lines[j], " ")
+			case lines[j] != "":
+				lines[j] = indent + lines[j]
+			}
+		}
+
+		renderedItems[i] = strings.Join(lines, "\n")
+	}
+
+	if loose {
+		return strings.Join(renderedItems, "\n\n")
+	}
+	return strings.Join(renderedItems, "\n")
+}
+
+// renderTable renders the table as GFM table. If the table can't be
+// represented in GFM, e.g. it has merged cells or block content inside
+// its cells, the table will be kept as HTML.
+func (r *markdownRenderer) renderTable(table *html.Node) []markdownBlock {
+	rows := tableRows(table)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if !isSimpleTable(rows) {
+		return []markdownBlock{{text: OuterHTML(table)}}
+	}
+
+	var blocks []markdownBlock
+	for child := table.FirstChild; child != nil; child = child.NextSibling {
+		if isHTMLElement(child, "caption") {
+			if caption := markdownParagraph(r.renderInlineChildren(child, false)); caption != "" {
+				blocks = append(blocks, markdownBlock{text: caption})
+			}
+		}
+	}
+
+	nColumns := 0
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		for _, cell := range tableCells(row) {
+			content := r.renderInlineChildren(cell, true)
+			cells[i] = append(cells[i], strings.ReplaceAll(content, "|", `\|`))
+		}
+
+		if len(cells[i]) > nColumns {
+			nColumns = len(cells[i])
+		}
+	}
+
+	// The first row is used as header, since GFM table requires it
+	headerCells := tableCells(rows[0])
+	delimiters := make([]string, nColumns)
+	for i := range delimiters {
+		align := ""
+		if i < len(headerCells) {
+			align = cellAlignment(headerCells[i])
+		}
+
+		switch align {
+		case "left":
+			delimiters[i] = ":---"
+		case "center":
+			delimiters[i] = ":---:"
+		case "right":
+			delimiters[i] = "---:"
+		default:
+			delimiters[i] = "---"
+		}
+	}
+
+	formatRow := func(row []string) string {
+		for len(row) < nColumns {
+			row = append(row, "")
+		}
+		return "| " + strings.Join(row, " | ") + " |"
+	}
+
+	lines := []string{formatRow(cells[0]), formatRow(delimiters)}
+	for _, row := range cells[1:] {
+		lines = append(lines, formatRow(row))
+	}
+
+	return append(blocks, markdownBlock{text: strings.Join(lines, "\n")})
+}
+
+func (r *markdownRenderer) resolveURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if r.baseURL == nil {
+		return rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return r.baseURL.ResolveReference(parsedURL).String()
+}
+
+// isMarkdownBlock check whether the node is rendered as block in Markdown.
+func isMarkdownBlock(node *html.Node) bool {
+	if node.Type != html.ElementNode || node.Namespace != "" {
+		return false
+	}
+
+	switch node.Data {
+	case "head", "script", "style", "template", "noscript", "title", "meta", "link",
+		"html", "body", "div", "section", "article", "main", "header", "footer",
+		"nav", "aside", "figure", "figcaption", "hgroup", "address", "center",
+		"h1", "h2", "h3", "h4", "h5", "h6", "p", "blockquote", "ul", "ol", "pre",
+		"hr", "table", "dl", "details", "dialog", "fieldset", "form", "iframe",
+		"video", "audio", "canvas", "object":
+		return true
+	default:
+		return false
+	}
+}
+
+// renderMarkdownCodeBlock renders <pre> as fenced code block. The language is
+// taken from the "language-x" or "lang-x" class of <pre> or its <code>.
+func renderMarkdownCodeBlock(pre *html.Node) string {
+	language := codeLanguage(pre)
+	if code := FirstElementChild(pre); language == "" && code != nil && code.Data == "code" {
+		language = codeLanguage(code)
+	}
+
+	code := strings.TrimSuffix(TextContent(pre), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(node *html.Node) string {
+	for _, class := range ClassList(node).Values() {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) && len(class) > len(prefix) {
+				return class[len(prefix):]
+			}
+		}
+	}
+	return ""
+}
+
+// renderMarkdownCodeSpan renders the code as code span, using backtick
+// string that longer than any backtick string inside the code.
+func renderMarkdownCodeSpan(code string) string {
+	code = strings.Join(strings.FieldsFunc(code, isASCIIWhitespace), " ")
+	if code == "" {
+		return ""
+	}
+
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+
+	return fence + code + fence
+}
+
+// formatMarkdownDestination formats the link destination and its title.
+func formatMarkdownDestination(destination, title string) string {
+	destination = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(destination)
+	if title == "" {
+		return "(" + destination + ")"
+	}
+
+	title = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(title)
+	return "(" + destination + ` "` + title + `")`
+}
+
+// escapeMarkdown escapes the characters that have special meaning in Markdown.
+func escapeMarkdown(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '\\', '*', '_', '`', '[', ']', '~':
+			sb.WriteByte('\\')
+		case '<':
+			if i+1 < len(text) && (isASCIIUpper(rune(text[i+1])) || isASCIILower(rune(text[i+1])) || strings.IndexByte("/!?", text[i+1]) >= 0) {
+				sb.WriteByte('\\')
+			}
+		}
+		sb.WriteByte(text[i])
+	}
+
+	return rxMarkdownEntity.ReplaceAllString(sb.String(), `\&$1`)
+}
+
+// markdownParagraph trims the paragraph and escapes the characters
+// that would be treated as block marker at the start of its lines.
+func markdownParagraph(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(text, "\\")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if rxMarkdownLineStart.MatchString(line) {
+			if line[0] >= '0' && line[0] <= '9' {
+				idx := strings.IndexAny(line, ".)")
+				lines[i] = line[:idx] + "\\" + line[idx:]
+			} else {
+				lines[i] = "\\" + line
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func joinMarkdownBlocks(blocks []markdownBlock, separator string) string {
+	texts := make([]string, len(blocks))
+	for i, block := range blocks {
+		texts[i] = block.text
+	}
+	return strings.Join(texts, separator)
+}
+
+// tableRows returns the rows of the table, without entering nested table.
+func tableRows(table *html.Node) []*html.Node {
+	var rows []*html.Node
+	for child := table.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case isHTMLElement(child, "tr"):
+			rows = append(rows, child)
+		case isHTMLElement(child, "thead"), isHTMLElement(child, "tbody"), isHTMLElement(child, "tfoot"):
+			for row := child.FirstChild; row != nil; row = row.NextSibling {
+				if isHTMLElement(row, "tr") {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	return rows
+}
+
+func tableCells(row *html.Node) []*html.Node {
+	var cells []*html.Node
+	for child := row.FirstChild; child != nil; child = child.NextSibling {
+		if isHTMLElement(child, "td") || isHTMLElement(child, "th") {
+			cells = append(cells, child)
+		}
+	}
+	return cells
+}
+
+// isSimpleTable check whether the table rows can be represented
+// in GFM table, i.e. there are no merged cells and no block
+// content (except paragraph) inside the cells.
+func isSimpleTable(rows []*html.Node) bool {
+	for _, row := range rows {
+		for _, cell := range tableCells(row) {
+			for _, attrName := range []string{"colspan", "rowspan"} {
+				if span, err := strconv.Atoi(GetAttribute(cell, attrName)); err == nil && span > 1 {
+					return false
+				}
+			}
+
+			for _, descendant := range GetElementsByTagName(cell, "*") {
+				if isMarkdownBlock(descendant) && descendant.Data != "p" && descendant.Data != "div" {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// cellAlignment returns the text alignment of table cell, either
+// from its align attribute or from its inline style.
+func cellAlignment(cell *html.Node) string {
+	align := Style(cell).GetPropertyValue("text-align")
+	if align == "" {
+		align = GetAttribute(cell, "align")
+	}
+	return strings.ToLower(strings.TrimSpace(align))
+}
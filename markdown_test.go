@@ -0,0 +1,54 @@
+package dom_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestToMarkdown(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.html"))
+	if err != nil {
+		t.Fatalf("ToMarkdown(), failed to list test files: %v", err)
+	}
+
+	opts := dom.MarkdownOptions{BaseURL: "https://example.com/articles/"}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".html")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("ToMarkdown(), failed to open source: %v", err)
+			}
+			defer f.Close()
+
+			doc, err := dom.Parse(f)
+			if err != nil {
+				t.Fatalf("ToMarkdown(), failed to parse: %v", err)
+			}
+
+			got := dom.ToMarkdown(doc, opts)
+			goldenPath := strings.TrimSuffix(path, ".html") + ".md"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+					t.Fatalf("ToMarkdown(), failed to update golden file: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("ToMarkdown(), failed to read golden file: %v", err)
+			}
+
+			if got != string(want) {
+				t.Errorf("ToMarkdown() mismatch\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package dom
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// minifyTextEscaper escapes the characters that must be escaped in text.
+	minifyTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\r", "&#13;")
+
+	// minifyAttrEscaper escapes the characters that must be escaped
+	// in double-quoted attribute value.
+	minifyAttrEscaper = strings.NewReplacer("&", "&amp;", `"`, "&#34;", "\r", "&#13;")
+)
+
+// booleanAttributes is the HTML attributes whose value is ignored,
+// so they can be written without value.
+var booleanAttributes = map[string]struct{}{
+	"allowfullscreen": {}, "async": {}, "autofocus": {}, "autoplay": {},
+	"checked": {}, "controls": {}, "default": {}, "defer": {}, "disabled": {},
+	"formnovalidate": {}, "inert": {}, "ismap": {}, "itemscope": {}, "loop": {},
+	"multiple": {}, "muted": {}, "nomodule": {}, "novalidate": {}, "open": {},
+	"playsinline": {}, "readonly": {}, "required": {}, "reversed": {}, "selected": {},
+}
+
+// MinifyOptions is the options for RenderMinified.
+type MinifyOptions struct {
+	// KeepConditionalComments keeps the conditional comments for
+	// Internet Explorer, e.g. <!--[if IE]>...<![endif]-->, while
+	// the other comments are removed.
+	KeepConditionalComments bool
+
+	// KeepOptionalTags disables omitting the optional tags.
+	KeepOptionalTags bool
+
+	// Cascade is used to decide where whitespace is significant.
+	// If it's nil, only the inline style is used.
+	Cascade *Cascade
+}
+
+// RenderMinified renders the node and its descendants as minified HTML. The
+// optional tags are omitted and the attribute values are unquoted where it's
+// allowed by HTML specification, comments are removed, boolean attributes
+// are written without value, and insignificant whitespace is collapsed or
+// removed. Whitespace is kept as it is in <pre>, <textarea> and elements
+// whose computed style preserves whitespace. The minified HTML is parsed
+// back into a tree that rendered the same as the original.
+func RenderMinified(w io.Writer, node *html.Node, opts MinifyOptions) error {
+	if node == nil {
+		return nil
+	}
+
+	m := minifier{
+		rw:      &renderWriter{w: w},
+		opts:    opts,
+		cascade: cascadeFor(opts.Cascade),
+	}
+
+	m.texts = collapseWhitespace(node, m.cascade)
+	m.writeNode(node)
+	return m.rw.err
+}
+
+type minifier struct {
+	rw      *renderWriter
+	opts    MinifyOptions
+	cascade *Cascade
+
+	// texts is the collapsed text of text nodes, whose
+	// whitespace is not significant.
+	texts map[*html.Node]string
+}
+
+func (m *minifier) writeNode(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		switch text, collapsed := m.texts[node]; {
+		case collapsed:
+			m.rw.writeString(minifyTextEscaper.Replace(text))
+		case isRawTextElement(node.Parent):
+			m.rw.writeString(node.Data)
+		default:
+			m.rw.writeString(minifyTextEscaper.Replace(node.Data))
+		}
+
+	case html.DocumentNode:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			m.writeNode(child)
+		}
+
+	case html.ElementNode:
+		m.writeElement(node)
+
+	case html.CommentNode:
+		if m.isCommentKept(node) {
+			m.rw.writeVerbatim(node)
+		}
+
+	default:
+		m.rw.writeVerbatim(node)
+	}
+}
+
+func (m *minifier) writeElement(node *html.Node) {
+	if m.opts.KeepOptionalTags || !m.canOmitStartTag(node) {
+		attr := ""
+		m.rw.writeString("<" + node.Data)
+		for i := range node.Attr {
+			attr = minifiedAttribute(node, node.Attr[i])
+			m.rw.writeString(" " + attr)
+		}
+
+		// Foreign element without content can be self-closed. The slash must
+		// be separated from unquoted value, otherwise it's part of the value.
+		if node.Namespace != "" && node.FirstChild == nil {
+			if strings.Contains(attr, "=") && !strings.HasSuffix(attr, `"`) {
+				m.rw.writeString(" ")
+			}
+			m.rw.writeString("/>")
+			return
+		}
+		m.rw.writeString(">")
+	}
+
+	if IsVoidElement(node) && node.Namespace == "" {
+		return
+	}
+
+	if needsLeadingNewline(node) {
+		m.rw.writeString("\n")
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		m.writeNode(child)
+	}
+
+	if m.opts.KeepOptionalTags || !m.canOmitEndTag(node) {
+		m.rw.writeString(endTag(node))
+	}
+}
+
+func (m *minifier) isCommentKept(node *html.Node) bool {
+	return m.opts.KeepConditionalComments && isConditionalComment(node.Data)
+}
+
+// isRendered check whether the node will be written in minified HTML.
+func (m *minifier) isRendered(node *html.Node) bool {
+	switch node.Type {
+	case html.CommentNode:
+		return m.isCommentKept(node)
+	case html.TextNode:
+		text, collapsed := m.texts[node]
+		return !collapsed || text != ""
+	default:
+		return true
+	}
+}
+
+// firstRenderedChild returns the first child that will be written.
+func (m *minifier) firstRenderedChild(node *html.Node) *html.Node {
+	child := node.FirstChild
+	for child != nil && !m.isRendered(child) {
+		child = child.NextSibling
+	}
+	return child
+}
+
+// nextRenderedSibling returns the next sibling that will be written.
+func (m *minifier) nextRenderedSibling(node *html.Node) *html.Node {
+	sibling := node.NextSibling
+	for sibling != nil && !m.isRendered(sibling) {
+		sibling = sibling.NextSibling
+	}
+	return sibling
+}
+
+// prevRenderedSibling returns the previous sibling that will be written.
+func (m *minifier) prevRenderedSibling(node *html.Node) *html.Node {
+	sibling := node.PrevSibling
+	for sibling != nil && !m.isRendered(sibling) {
+		sibling = sibling.PrevSibling
+	}
+	return sibling
+}
+
+// canOmitStartTag check whether the start tag of the element can be
+// omitted, following the optional tags rules in HTML specification.
+func (m *minifier) canOmitStartTag(node *html.Node) bool {
+	if node.Namespace != "" || len(node.Attr) > 0 {
+		return false
+	}
+
+	first := m.firstRenderedChild(node)
+	switch node.Data {
+	case "html":
+		return first == nil || first.Type != html.CommentNode
+
+	case "head":
+		return first == nil || first.Type == html.ElementNode
+
+	case "body":
+		if first == nil {
+			return true
+		}
+
+		switch first.Type {
+		case html.CommentNode:
+			return false
+		case html.TextNode:
+			return !startsWithASCIIWhitespace(m.renderedText(first))
+		case html.ElementNode:
+			switch first.Data {
+			case "meta", "noscript", "link", "script", "style", "template":
+				return false
+			}
+		}
+		return true
+
+	case "colgroup", "tbody":
+		child := "col"
+		if node.Data == "tbody" {
+			child = "tr"
+		}
+
+		if first == nil || !isHTMLElement(first, child) {
+			return false
+		}
+
+		// Omitted start tag can't be preceded by the same
+		// group element whose end tag is omitted
+		prev := m.prevRenderedSibling(node)
+		if prev != nil && prev.Type == html.ElementNode &&
+			(prev.Data == node.Data || (node.Data == "tbody" && (prev.Data == "thead" || prev.Data == "tfoot"))) {
+			return m.opts.KeepOptionalTags || !m.canOmitEndTag(prev)
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+// canOmitEndTag check whether the end tag of the element can be
+// omitted, following the optional tags rules in HTML specification.
+func (m *minifier) canOmitEndTag(node *html.Node) bool {
+	if node.Namespace != "" {
+		return false
+	}
+
+	next := m.nextRenderedSibling(node)
+	nextIs := func(tagNames ...string) bool {
+		for _, tagName := range tagNames {
+			if next != nil && isHTMLElement(next, tagName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch node.Data {
+	case "html", "body":
+		return next == nil || next.Type != html.CommentNode
+
+	case "head", "colgroup", "caption":
+		switch {
+		case next == nil:
+			return true
+		case next.Type == html.CommentNode:
+			return false
+		case next.Type == html.TextNode:
+			return !startsWithASCIIWhitespace(m.renderedText(next))
+		default:
+			return true
+		}
+
+	case "li":
+		return next == nil || nextIs("li")
+
+	case "dt":
+		return nextIs("dt", "dd")
+
+	case "dd":
+		return next == nil || nextIs("dd", "dt")
+
+	case "p":
+		if next == nil {
+			parent := node.Parent
+			if parent == nil || parent.Type != html.ElementNode {
+				return true
+			}
+
+			switch parent.Data {
+			case "a", "audio", "del", "ins", "map", "noscript", "video":
+				return false
+			}
+			return !strings.Contains(parent.Data, "-")
+		}
+
+		return nextIs("address", "article", "aside", "blockquote", "details", "dialog",
+			"div", "dl", "fieldset", "figcaption", "figure", "footer", "form", "h1", "h2",
+			"h3", "h4", "h5", "h6", "header", "hgroup", "hr", "main", "menu", "nav", "ol",
+			"p", "pre", "search", "section", "table", "ul")
+
+	case "rt", "rp":
+		return next == nil || nextIs("rt", "rp")
+
+	case "optgroup":
+		return next == nil || nextIs("optgroup", "hr")
+
+	case "option":
+		return next == nil || nextIs("option", "optgroup", "hr")
+
+	case "thead":
+		return nextIs("tbody", "tfoot")
+
+	case "tbody":
+		return next == nil || nextIs("tbody", "tfoot")
+
+	case "tfoot":
+		return next == nil
+
+	case "tr":
+		return next == nil || nextIs("tr")
+
+	case "td", "th":
+		return next == nil || nextIs("td", "th")
+
+	default:
+		return false
+	}
+}
+
+// renderedText returns the text of the text node as it will be written.
+func (m *minifier) renderedText(node *html.Node) string {
+	if text, collapsed := m.texts[node]; collapsed {
+		return text
+	}
+	return node.Data
+}
+
+// minifiedAttribute returns the attribute in its shortest form. Empty and
+// boolean attributes are written without value, and the value is unquoted
+// if it doesn't contain any characters that not allowed in unquoted value.
+func minifiedAttribute(node *html.Node, attr html.Attribute) string {
+	name := attributeQualifiedName(attr)
+	if attr.Val == "" {
+		return name
+	}
+
+	if _, isBoolean := booleanAttributes[name]; isBoolean && node.Namespace == "" &&
+		attr.Namespace == "" && strings.EqualFold(attr.Val, name) {
+		return name
+	}
+
+	if strings.ContainsAny(attr.Val, " \t\n\f\r\"'=<>`") {
+		return name + `="` + minifyAttrEscaper.Replace(attr.Val) + `"`
+	}
+
+	return name + "=" + strings.ReplaceAll(attr.Val, "&", "&amp;")
+}
+
+// isConditionalComment check whether the comment is a conditional comment
+// for Internet Explorer, including the downlevel-revealed one.
+func isConditionalComment(data string) bool {
+	return strings.HasPrefix(data, "[if ") || data == "<![endif]"
+}
+
+func startsWithASCIIWhitespace(text string) bool {
+	return text != "" && isASCIIWhitespace(rune(text[0]))
+}
@@ -0,0 +1,133 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestRenderMinified(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		opts       dom.MinifyOptions
+		want       string
+	}{{
+		name: "optional tags",
+		htmlSource: `<!DOCTYPE html><html><head><title>Test</title></head><body>` +
+			`<p>First</p><p>Second</p><ul><li>One</li><li>Two</li></ul></body></html>`,
+		want: `<!DOCTYPE html><title>Test</title><p>First<p>Second<ul><li>One<li>Two</ul>`,
+	}, {
+		name:       "keep optional tags",
+		htmlSource: `<p>First</p><p>Second</p>`,
+		opts:       dom.MinifyOptions{KeepOptionalTags: true},
+		want:       `<html><head></head><body><p>First</p><p>Second</p></body></html>`,
+	}, {
+		name:       "paragraph inside anchor",
+		htmlSource: `<body><a href="#"><p>Para</p></a><p>Last</p></body>`,
+		want:       `<a href=#><p>Para</p></a><p>Last`,
+	}, {
+		name: "table",
+		htmlSource: `<table><thead><tr><th>A</th><th>B</th></tr></thead>` +
+			`<tbody><tr><td>1</td><td>2</td></tr><tr><td>3</td><td>4</td></tr></tbody></table>`,
+		want: `<table><thead><tr><th>A<th>B<tbody><tr><td>1<td>2<tr><td>3<td>4</table>`,
+	}, {
+		name:       "attributes",
+		htmlSource: `<body><input type="checkbox" checked="checked" disabled="" value="a b" data-x="it's" name="q&amp;a"></body>`,
+		want:       `<input type=checkbox checked disabled value="a b" data-x="it's" name=q&amp;a>`,
+	}, {
+		name:       "whitespace",
+		htmlSource: "<body>\n  <div>\n    Hello   <b> big </b>\n  world\n  </div>\n  <p>  a <br>  b  </p>\n</body>",
+		want:       "<div>Hello <b>big </b>world</div><p>a<br>b",
+	}, {
+		name:       "preserved whitespace",
+		htmlSource: "<body><pre>\n\n  keep   this </pre><textarea> and  this </textarea><span style=\"white-space: pre-wrap\"> and   this </span></body>",
+		want:       "<pre>\n\n  keep   this </pre><textarea> and  this </textarea><span style=\"white-space: pre-wrap\"> and   this </span>",
+	}, {
+		name:       "comments",
+		htmlSource: `<body><!-- removed --><p>Text</p><!--[if IE]><p>IE only</p><![endif]--></body>`,
+		opts:       dom.MinifyOptions{KeepConditionalComments: true},
+		want:       `<p>Text</p><!--[if IE]><p>IE only</p><![endif]-->`,
+	}, {
+		name:       "script and svg",
+		htmlSource: `<body><script>if (a < b && c) { go() }</script><svg viewBox="0 0 10 10"><circle r="5"></circle></svg></body>`,
+		want:       `<body><script>if (a < b && c) { go() }</script><svg viewBox="0 0 10 10"><circle r=5 /></svg>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.Parse(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("RenderMinified(), failed to parse: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := dom.RenderMinified(&sb, doc, tt.opts); err != nil {
+				t.Fatalf("RenderMinified() error = %v", err)
+			}
+
+			got := sb.String()
+			if got != tt.want {
+				t.Errorf("RenderMinified() = %q, want %q", got, tt.want)
+			}
+
+			// Minified HTML must be parsed back into equivalent tree
+			reparsed, err := dom.Parse(strings.NewReader(got))
+			if err != nil {
+				t.Fatalf("RenderMinified(), failed to parse result: %v", err)
+			}
+
+			if gotElements, wantElements := elementSignature(reparsed), elementSignature(doc); gotElements != wantElements {
+				t.Errorf("RenderMinified() elements = %q, want %q", gotElements, wantElements)
+			}
+
+			wantText := dom.InnerText(dom.QuerySelector(doc, "body"))
+			gotText := dom.InnerText(dom.QuerySelector(reparsed, "body"))
+			if gotText != wantText {
+				t.Errorf("RenderMinified() text = %q, want %q", gotText, wantText)
+			}
+		})
+	}
+
+	t.Run("custom cascade", func(t *testing.T) {
+		doc, err := dom.Parse(strings.NewReader("<link rel=\"stylesheet\" href=\"main.css\"><div class=\"code\">a\n  b</div>"))
+		if err != nil {
+			t.Fatalf("RenderMinified(), failed to parse: %v", err)
+		}
+
+		cascade := dom.NewCascade(doc, dom.CascadeOptions{
+			ResolveStylesheet: func(string) (string, bool) { return ".code { white-space: pre }", true },
+		})
+
+		var sb strings.Builder
+		div := dom.QuerySelector(doc, "div")
+		if err := dom.RenderMinified(&sb, div, dom.MinifyOptions{Cascade: cascade}); err != nil {
+			t.Fatalf("RenderMinified() error = %v", err)
+		}
+
+		if got, want := sb.String(), "<div class=code>a\n  b</div>"; got != want {
+			t.Errorf("RenderMinified() = %q, want %q", got, want)
+		}
+	})
+}
+
+// elementSignature returns the tag name and attributes
+// of all elements in the tree, in document order.
+func elementSignature(root *html.Node) string {
+	var sb strings.Builder
+	for _, element := range dom.GetElementsByTagName(root, "*") {
+		sb.WriteString("<" + element.Data)
+		for _, attr := range element.Attr {
+			// Boolean attribute might be written without its value
+			value := attr.Val
+			if value == attr.Key {
+				value = ""
+			}
+			sb.WriteString(" " + attr.Key + "=" + value)
+		}
+		sb.WriteString(">")
+	}
+	return sb.String()
+}
@@ -0,0 +1,242 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Namespace URIs that used in HTML document.
+const (
+	HTMLNamespace   = "http://www.w3.org/1999/xhtml"
+	SVGNamespace    = "http://www.w3.org/2000/svg"
+	MathMLNamespace = "http://www.w3.org/1998/Math/MathML"
+	XLinkNamespace  = "http://www.w3.org/1999/xlink"
+	XMLNamespace    = "http://www.w3.org/XML/1998/namespace"
+	XMLNSNamespace  = "http://www.w3.org/2000/xmlns/"
+)
+
+// ErrInvalidNamespace is returned by SetAttributeNS when the attribute can't
+// be stored in its namespace, e.g. attribute in custom namespace that doesn't
+// have prefix, or whose prefix is already declared for another namespace.
+var ErrInvalidNamespace = errors.New("dom: attribute can't be stored in namespace")
+
+// html.Node doesn't store namespace URI. Instead, it uses short names for
+// the namespace of elements ("svg" and "math") and attributes ("xlink", "xml"
+// and "xmlns"). These maps are used to convert between both of them.
+var (
+	elementNamespaces = map[string]string{
+		HTMLNamespace:   "",
+		SVGNamespace:    "svg",
+		MathMLNamespace: "math",
+	}
+
+	attributeNamespaces = map[string]string{
+		XLinkNamespace: "xlink",
+		XMLNamespace:   "xml",
+		XMLNSNamespace: "xmlns",
+	}
+)
+
+// NamespaceURI returns the namespace URI of the element, or empty
+// string if it's not an element.
+func NamespaceURI(node *html.Node) string {
+	if node == nil || node.Type != html.ElementNode {
+		return ""
+	}
+
+	for uri, namespace := range elementNamespaces {
+		if node.Namespace == namespace {
+			return uri
+		}
+	}
+
+	return node.Namespace
+}
+
+// CreateElementNS creates a new ElementNode with the specified namespace URI
+// and qualified name. Since html.Node doesn't store prefix, the prefix in
+// qualified name (if any) will be dropped.
+func CreateElementNS(namespaceURI string, qualifiedName string) *html.Node {
+	_, localName := splitQualifiedName(qualifiedName)
+
+	namespace, known := elementNamespaces[namespaceURI]
+	if !known {
+		namespace = namespaceURI
+	}
+
+	return &html.Node{
+		Type:      html.ElementNode,
+		Data:      localName,
+		Namespace: namespace,
+	}
+}
+
+// GetAttributeNS returns the value of attribute with the specified
+// namespace URI and local name. If the given attribute does not exist,
+// the value returned will be an empty string.
+func GetAttributeNS(node *html.Node, namespaceURI string, localName string) string {
+	if idx := attributeNSIndex(node, namespaceURI, localName); idx >= 0 {
+		return node.Attr[idx].Val
+	}
+	return ""
+}
+
+// SetAttributeNS sets attribute with the specified namespace URI and
+// qualified name. If attribute already exists, it will be replaced.
+// Since html.Attribute only stores namespace prefix, attribute in namespace
+// other than XLink, XML and XMLNS is stored using the prefix of its qualified
+// name, and the prefix is declared in the element using xmlns attribute if
+// it's not declared yet, so it can be looked up again by its namespace URI.
+// Returns ErrInvalidNamespace and leaves the element unchanged if such
+// attribute doesn't have prefix, or if the prefix is already declared in
+// the element for another namespace.
+func SetAttributeNS(node *html.Node, namespaceURI string, qualifiedName string, attrValue string) error {
+	prefix, localName := splitQualifiedName(qualifiedName)
+	if idx := attributeNSIndex(node, namespaceURI, localName); idx >= 0 {
+		node.Attr[idx].Val = attrValue
+		return nil
+	}
+
+	namespace, known := attributeNamespaces[namespaceURI]
+	switch {
+	case namespaceURI == "":
+		namespace = ""
+	case namespaceURI == XMLNSNamespace && localName == "xmlns":
+		namespace = ""
+	case !known:
+		if err := declarePrefix(node, prefix, namespaceURI); err != nil {
+			return fmt.Errorf("%w: %q in %q: %v", ErrInvalidNamespace, qualifiedName, namespaceURI, err)
+		}
+		namespace = prefix
+	}
+
+	node.Attr = append(node.Attr, html.Attribute{
+		Namespace: namespace,
+		Key:       localName,
+		Val:       attrValue,
+	})
+	return nil
+}
+
+// RemoveAttributeNS removes attribute with the specified namespace URI
+// and local name.
+func RemoveAttributeNS(node *html.Node, namespaceURI string, localName string) {
+	if idx := attributeNSIndex(node, namespaceURI, localName); idx >= 0 {
+		node.Attr = append(node.Attr[:idx], node.Attr[idx+1:]...)
+	}
+}
+
+// HasAttributeNS returns a Boolean value indicating whether the specified
+// node has attribute with the specified namespace URI and local name.
+func HasAttributeNS(node *html.Node, namespaceURI string, localName string) bool {
+	return attributeNSIndex(node, namespaceURI, localName) >= 0
+}
+
+// attributeNSIndex returns index of the attribute with the specified
+// namespace URI and local name, or -1 if it doesn't exist.
+func attributeNSIndex(node *html.Node, namespaceURI string, localName string) int {
+	for i, attr := range node.Attr {
+		if attr.Key != localName {
+			continue
+		}
+
+		uri := attributeNamespaceURI(attr)
+		if _, known := attributeNamespaces[uri]; !known && attr.Namespace != "" {
+			uri = lookupPrefixURI(node, attr.Namespace)
+		}
+
+		if uri == namespaceURI {
+			return i
+		}
+	}
+	return -1
+}
+
+// declarePrefix declares the prefix for the namespace URI in the element,
+// unless it's already declared by the element or its ancestors. Returns
+// error if the prefix can't be used for the namespace.
+func declarePrefix(node *html.Node, prefix string, namespaceURI string) error {
+	switch prefix {
+	case "":
+		return errors.New("missing prefix")
+	case "xmlns", "xml":
+		return fmt.Errorf("reserved prefix %q", prefix)
+	}
+
+	switch uri, declared := declaredPrefixURI(node, prefix); {
+	case declared && uri != namespaceURI:
+		return fmt.Errorf("prefix %q is declared for %q", prefix, uri)
+	case declared, lookupPrefixURI(node, prefix) == namespaceURI:
+		return nil
+	}
+
+	node.Attr = append(node.Attr, html.Attribute{
+		Namespace: "xmlns",
+		Key:       prefix,
+		Val:       namespaceURI,
+	})
+	return nil
+}
+
+// lookupPrefixURI returns the namespace URI of the prefix that declared in
+// the element or its nearest ancestor. If it's not declared, the prefix
+// itself is returned.
+func lookupPrefixURI(node *html.Node, prefix string) string {
+	for n := node; n != nil; n = n.Parent {
+		if uri, declared := declaredPrefixURI(n, prefix); declared {
+			return uri
+		}
+	}
+	return prefix
+}
+
+// declaredPrefixURI returns the namespace URI of the prefix that declared
+// in the element, either by the parser (xmlns:xlink in foreign content)
+// or as plain attribute (e.g. xmlns:epub in HTML element).
+func declaredPrefixURI(node *html.Node, prefix string) (string, bool) {
+	for _, attr := range node.Attr {
+		if (attr.Namespace == "xmlns" && attr.Key == prefix) ||
+			(attr.Namespace == "" && attr.Key == "xmlns:"+prefix) {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// attributeNamespaceURI returns the namespace URI of the attribute. For
+// attribute whose prefix is not one of the known namespaces, the prefix
+// is returned as it is, since it can only be resolved using its element.
+func attributeNamespaceURI(attr html.Attribute) string {
+	// Parser keeps `xmlns` attribute as it is, without namespace
+	if attr.Namespace == "" && attr.Key == "xmlns" {
+		return XMLNSNamespace
+	}
+
+	for uri, namespace := range attributeNamespaces {
+		if attr.Namespace == namespace {
+			return uri
+		}
+	}
+
+	return attr.Namespace
+}
+
+// attributeQualifiedName returns the name of attribute including
+// its namespace prefix, e.g. "xlink:href".
+func attributeQualifiedName(attr html.Attribute) string {
+	if attr.Namespace == "" {
+		return attr.Key
+	}
+	return attr.Namespace + ":" + attr.Key
+}
+
+// splitQualifiedName splits qualified name into its prefix and local name.
+func splitQualifiedName(qualifiedName string) (string, string) {
+	if idx := strings.Index(qualifiedName, ":"); idx >= 0 {
+		return qualifiedName[:idx], qualifiedName[idx+1:]
+	}
+	return "", qualifiedName
+}
@@ -0,0 +1,283 @@
+package dom_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestCreateElementNS(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespaceURI  string
+		qualifiedName string
+		wantTag       string
+		wantNamespace string
+	}{{
+		name:          "html element",
+		namespaceURI:  dom.HTMLNamespace,
+		qualifiedName: "div",
+		wantTag:       "div",
+		wantNamespace: dom.HTMLNamespace,
+	}, {
+		name:          "svg element",
+		namespaceURI:  dom.SVGNamespace,
+		qualifiedName: "svg:rect",
+		wantTag:       "rect",
+		wantNamespace: dom.SVGNamespace,
+	}, {
+		name:          "camel case svg element",
+		namespaceURI:  dom.SVGNamespace,
+		qualifiedName: "foreignObject",
+		wantTag:       "foreignObject",
+		wantNamespace: dom.SVGNamespace,
+	}, {
+		name:          "mathml element",
+		namespaceURI:  dom.MathMLNamespace,
+		qualifiedName: "mfrac",
+		wantTag:       "mfrac",
+		wantNamespace: dom.MathMLNamespace,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := dom.CreateElementNS(tt.namespaceURI, tt.qualifiedName)
+			if got := dom.TagName(node); got != tt.wantTag {
+				t.Errorf("CreateElementNS() tag = %v, want %v", got, tt.wantTag)
+			}
+
+			if got := dom.NamespaceURI(node); got != tt.wantNamespace {
+				t.Errorf("CreateElementNS() namespace = %v, want %v", got, tt.wantNamespace)
+			}
+		})
+	}
+}
+
+func TestAttributeNS(t *testing.T) {
+	htmlSource := `<svg xmlns="http://www.w3.org/2000/svg" ` +
+		`xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10">` +
+		`<use xlink:href="#icon" href="#plain"></use></svg>`
+
+	doc, err := parseHTMLSource(htmlSource)
+	if err != nil {
+		t.Fatalf("AttributeNS(), failed to parse: %v", err)
+	}
+
+	svg := dom.QuerySelector(doc, "svg")
+	use := dom.QuerySelector(doc, "use")
+
+	t.Run("get attribute", func(t *testing.T) {
+		tests := []struct {
+			node         *html.Node
+			namespaceURI string
+			localName    string
+			want         string
+		}{
+			{svg, "", "viewBox", "0 0 10 10"},
+			{svg, dom.XMLNSNamespace, "xmlns", dom.SVGNamespace},
+			{svg, dom.XMLNSNamespace, "xlink", dom.XLinkNamespace},
+			{use, dom.XLinkNamespace, "href", "#icon"},
+			{use, "", "href", "#plain"},
+			{use, dom.XLinkNamespace, "title", ""},
+		}
+
+		for _, tt := range tests {
+			if got := dom.GetAttributeNS(tt.node, tt.namespaceURI, tt.localName); got != tt.want {
+				t.Errorf("GetAttributeNS(%q, %q) = %v, want %v", tt.namespaceURI, tt.localName, got, tt.want)
+			}
+		}
+
+		// Non-NS variant matches the name regardless of namespace
+		if got := dom.GetAttribute(use, "href"); got != "#icon" {
+			t.Errorf("GetAttribute(href) = %v, want %v", got, "#icon")
+		}
+
+		if got := dom.GetAttribute(use, "xlink:href"); got != "" {
+			t.Errorf("GetAttribute(xlink:href) = %v, want empty", got)
+		}
+	})
+
+	t.Run("set, has and remove attribute", func(t *testing.T) {
+		node := dom.CreateElementNS(dom.SVGNamespace, "image")
+		dom.SetAttributeNS(node, dom.XLinkNamespace, "xlink:href", "a.png")
+		dom.SetAttributeNS(node, dom.XLinkNamespace, "xlink:href", "b.png")
+		dom.SetAttributeNS(node, "", "width", "10")
+
+		want := `<image xlink:href="b.png" width="10"></image>`
+		if got := dom.OuterHTML(node); got != want {
+			t.Errorf("SetAttributeNS() = %v, want %v", got, want)
+		}
+
+		if !dom.HasAttributeNS(node, dom.XLinkNamespace, "href") {
+			t.Errorf("HasAttributeNS() = false, want true")
+		}
+
+		if dom.HasAttributeNS(node, "", "href") {
+			t.Errorf("HasAttributeNS() = true, want false")
+		}
+
+		dom.RemoveAttributeNS(node, dom.XLinkNamespace, "href")
+		want = `<image width="10"></image>`
+		if got := dom.OuterHTML(node); got != want {
+			t.Errorf("RemoveAttributeNS() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("custom namespace", func(t *testing.T) {
+		const epubNamespace = "http://www.idpf.org/2007/ops"
+		const otherNamespace = "http://example.com/ns"
+
+		section := dom.CreateElement("section")
+		if err := dom.SetAttributeNS(section, epubNamespace, "epub:type", "chapter"); err != nil {
+			t.Fatalf("SetAttributeNS() error = %v", err)
+		}
+
+		if got := dom.GetAttributeNS(section, epubNamespace, "type"); got != "chapter" {
+			t.Errorf("GetAttributeNS() = %v, want %v", got, "chapter")
+		}
+
+		want := `<section xmlns:epub="http://www.idpf.org/2007/ops" epub:type="chapter"></section>`
+		if got := dom.OuterHTML(section); got != want {
+			t.Errorf("SetAttributeNS() = %v, want %v", got, want)
+		}
+
+		// The prefix that declared by ancestor is reused
+		p := dom.CreateElement("p")
+		dom.AppendChild(section, p)
+		dom.SetAttributeNS(p, epubNamespace, "epub:type", "note")
+		if got := dom.OuterHTML(p); got != `<p epub:type="note"></p>` {
+			t.Errorf("SetAttributeNS() = %v, want %v", got, `<p epub:type="note"></p>`)
+		}
+
+		if got := dom.GetAttributeNS(p, epubNamespace, "type"); got != "note" {
+			t.Errorf("GetAttributeNS() = %v, want %v", got, "note")
+		}
+
+		if dom.HasAttributeNS(p, otherNamespace, "type") {
+			t.Errorf("HasAttributeNS() with other namespace = true, want false")
+		}
+
+		// The prefix that already declared in the element for
+		// another namespace, or missing prefix, is rejected
+		for _, name := range []string{"epub:role", "role", "xml:role"} {
+			if err := dom.SetAttributeNS(section, otherNamespace, name, "x"); !errors.Is(err, dom.ErrInvalidNamespace) {
+				t.Errorf("SetAttributeNS(%q) error = %v, want %v", name, err, dom.ErrInvalidNamespace)
+			}
+		}
+
+		if got := dom.OuterHTML(section); got != want[:len(want)-len("</section>")]+`<p epub:type="note"></p></section>` {
+			t.Errorf("SetAttributeNS() with conflicting prefix = %v", got)
+		}
+
+		dom.RemoveAttributeNS(p, epubNamespace, "type")
+		if dom.HasAttributeNS(p, epubNamespace, "type") {
+			t.Errorf("RemoveAttributeNS() doesn't remove the attribute")
+		}
+	})
+
+	t.Run("set attribute without namespace", func(t *testing.T) {
+		image := dom.CreateElementNS(dom.SVGNamespace, "image")
+		dom.SetAttribute(image, "xlink:href", "a.png")
+		if got := dom.GetAttribute(image, "xlink:href"); got != "a.png" {
+			t.Errorf("GetAttribute(xlink:href) = %v, want %v", got, "a.png")
+		}
+
+		if dom.HasAttributeNS(image, dom.XLinkNamespace, "href") {
+			t.Errorf("HasAttributeNS() = true, want false")
+		}
+
+		dom.RemoveAttribute(image, "xlink:href")
+		if dom.HasAttribute(image, "xlink:href") {
+			t.Errorf("RemoveAttribute() doesn't remove the attribute")
+		}
+	})
+}
+
+func TestNamespaceRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		selector   string
+		namespace  string
+	}{{
+		name: "svg",
+		htmlSource: `<svg viewBox="0 0 24 24"><foreignObject><p>Hi</p></foreignObject>` +
+			`<use xlink:href="#icon"></use></svg>`,
+		selector:  "svg",
+		namespace: dom.SVGNamespace,
+	}, {
+		name:       "mathml",
+		htmlSource: `<math><mfrac><mi>a</mi><mi>b</mi></mfrac></math>`,
+		selector:   "math",
+		namespace:  dom.MathMLNamespace,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Fatalf("NamespaceRoundTrip(), failed to parse: %v", err)
+			}
+
+			// Serialize then parse again
+			root := dom.QuerySelector(doc, tt.selector)
+			if got := dom.OuterHTML(root); got != tt.htmlSource {
+				t.Errorf("OuterHTML() = %v, want %v", got, tt.htmlSource)
+			}
+
+			reparsed, err := parseHTMLSource(dom.OuterHTML(root))
+			if err != nil {
+				t.Fatalf("NamespaceRoundTrip(), failed to parse: %v", err)
+			}
+
+			reparsedRoot := dom.QuerySelector(reparsed, tt.selector)
+			assertSameNamespaces(t, root, reparsedRoot)
+
+			// Clone must keep the namespace as well
+			assertSameNamespaces(t, root, dom.Clone(root, true))
+
+			// Setting inner HTML must parse it in foreign context
+			target := dom.Clone(root, false)
+			dom.SetInnerHTML(target, dom.InnerHTML(root))
+			assertSameNamespaces(t, root, target)
+		})
+	}
+}
+
+func assertSameNamespaces(t *testing.T, want, got *html.Node) {
+	t.Helper()
+
+	wantNodes := dom.GetElementsByTagName(want, "*")
+	gotNodes := dom.GetElementsByTagName(got, "*")
+	if len(wantNodes) != len(gotNodes) {
+		t.Errorf("element count = %v, want %v", len(gotNodes), len(wantNodes))
+		return
+	}
+
+	for i := range wantNodes {
+		w, g := wantNodes[i], gotNodes[i]
+		if w.Data != g.Data || w.Namespace != g.Namespace {
+			t.Errorf("element %d = %s:%s, want %s:%s", i, g.Namespace, g.Data, w.Namespace, w.Data)
+		}
+
+		for _, attr := range w.Attr {
+			if val := dom.GetAttributeNS(g, attributeNamespaceURI(attr), attr.Key); val != attr.Val {
+				t.Errorf("attribute %s:%s of %s = %v, want %v", attr.Namespace, attr.Key, w.Data, val, attr.Val)
+			}
+		}
+	}
+}
+
+func attributeNamespaceURI(attr html.Attribute) string {
+	switch attr.Namespace {
+	case "xlink":
+		return dom.XLinkNamespace
+	case "xml":
+		return dom.XMLNamespace
+	case "xmlns":
+		return dom.XMLNSNamespace
+	}
+	return ""
+}
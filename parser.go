@@ -20,12 +20,86 @@ func FastParse(r io.Reader) (*html.Node, error) {
 	return html.Parse(r)
 }
 
+// FastParseDocument works like FastParse, except it returns the parsed node
+// wrapped in a Document.
+func FastParseDocument(r io.Reader) (*Document, error) {
+	return FastParseDocumentWithOptions(r, ParseOptions{})
+}
+
+// FastParseDocumentWithOptions is like FastParseDocument, but
+// parses the document following the specified options.
+func FastParseDocumentWithOptions(r io.Reader, opts ParseOptions) (*Document, error) {
+	var source []byte
+	if opts.PreserveSource || opts.TrackPositions {
+		var err error
+		if source, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(source)
+	}
+
+	root, err := FastParse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := NewDocument(root)
+	if source != nil {
+		doc.source = newSourceMap(source, source, nil, nil, root)
+	}
+
+	if opts.RewriteMetaCharset {
+		doc.SetMetaCharset("utf-8")
+	}
+
+	return doc, nil
+}
+
 // Parse parses html.Node from the specified reader while converting the character
 // encoding into UTF-8. This function is useful to correctly parse web pages that
 // uses custom text encoding, e.g. web pages from Asian websites. However, since it
 // has to detect charset before parsing, this function is quite slow and expensive
 // so if you sure the reader uses valid UTF-8, just use FastParse.
 func Parse(r io.Reader) (*html.Node, error) {
+	doc, err := ParseDocument(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Node, nil
+}
+
+// ParseOptions is the options for ParseDocumentWithOptions.
+type ParseOptions struct {
+	// RewriteMetaCharset updates the encoding that declared by the document
+	// to UTF-8, since the parsed document has been converted into UTF-8. If
+	// the document doesn't declare its encoding, <meta charset="utf-8"> will
+	// be added. The original encoding is still available from CharacterSet.
+	RewriteMetaCharset bool
+
+	// PreserveSource keeps the source of the document and records the source
+	// of each node, so the document can be rendered using RenderPreserving.
+	// To make the nodes match their source, the text is not normalized. The
+	// document is rendered in its original encoding, unless it's combined
+	// with RewriteMetaCharset which makes it rendered in UTF-8.
+	PreserveSource bool
+
+	// TrackPositions records the position of each node in the original input,
+	// which available from the Position method of the parsed document. Like
+	// PreserveSource, the text is not normalized.
+	TrackPositions bool
+}
+
+// ParseDocument works like Parse, except it returns the parsed node wrapped in
+// a Document. The detected character encoding is available from its
+// CharacterSet method.
+func ParseDocument(r io.Reader) (*Document, error) {
+	return ParseDocumentWithOptions(r, ParseOptions{})
+}
+
+// ParseDocumentWithOptions is like ParseDocument, but
+// parses the document following the specified options.
+func ParseDocumentWithOptions(r io.Reader, opts ParseOptions) (*Document, error) {
 	// Split the reader using tee
 	content, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -38,16 +112,47 @@ func Parse(r io.Reader) (*html.Node, error) {
 		return nil, err
 	}
 
-	pageEncoding, _ := charset.Lookup(res.Charset)
+	pageEncoding, encodingName := charset.Lookup(res.Charset)
 	if pageEncoding == nil {
-		pageEncoding = xunicode.UTF8
+		pageEncoding, encodingName = xunicode.UTF8, "utf-8"
 	}
 
 	// Parse HTML using the page encoding
-	r = bytes.NewReader(content)
-	r = transform.NewReader(r, pageEncoding.NewDecoder())
-	r = normalizeTextEncoding(r)
-	return html.Parse(r)
+	var source []byte
+	var offsets []sourceOffset
+	if opts.PreserveSource || opts.TrackPositions {
+		if source, offsets, err = decodeSource(content, pageEncoding); err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(source)
+	} else {
+		r = bytes.NewReader(content)
+		r = transform.NewReader(r, pageEncoding.NewDecoder())
+		r = normalizeTextEncoding(r)
+	}
+
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := NewDocument(root)
+	doc.charset = encodingName
+	if source != nil {
+		// Once the declared encoding is rewritten, the document
+		// must be rendered as UTF-8 instead of its original encoding
+		renderEncoding := pageEncoding
+		if opts.RewriteMetaCharset {
+			renderEncoding = nil
+		}
+		doc.source = newSourceMap(content, source, offsets, renderEncoding, root)
+	}
+
+	if opts.RewriteMetaCharset {
+		doc.SetMetaCharset("utf-8")
+	}
+
+	return doc, nil
 }
 
 // normalizeTextEncoding convert text encoding from NFD to NFC.
@@ -0,0 +1,353 @@
+package dom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrInvalidPatch is returned by ApplyPatch when an edit can't be applied
+// to the tree, e.g. its path doesn't point to an existing node.
+var ErrInvalidPatch = errors.New("dom: invalid patch")
+
+// ApplyPatch applies the edits that produced by Diff into the tree in root, and
+// returns the root of the edited tree. The root is edited in place, except when
+// it's replaced by EditReplace, in which case the new root is returned. The
+// inserted nodes are cloned, so the edits can be applied more than once. If an
+// edit can't be applied, the edits before it are kept and ErrInvalidPatch is
+// returned.
+func ApplyPatch(root *html.Node, edits []Edit) (*html.Node, error) {
+	for i, edit := range edits {
+		var err error
+		if root, err = applyEdit(root, edit); err != nil {
+			return root, fmt.Errorf("%w: edit %d (%s %s): %v",
+				ErrInvalidPatch, i, edit.Type, formatPath(edit.Path), err)
+		}
+	}
+	return root, nil
+}
+
+func applyEdit(root *html.Node, edit Edit) (*html.Node, error) {
+	switch edit.Type {
+	case EditInsert:
+		if edit.Node == nil || len(edit.Path) == 0 {
+			return root, errors.New("missing node or parent")
+		}
+
+		parent, err := nodeAtPath(root, edit.Path[:len(edit.Path)-1])
+		if err != nil {
+			return root, err
+		}
+
+		reference, err := childAtIndex(parent, edit.Path[len(edit.Path)-1], true)
+		if err != nil {
+			return root, err
+		}
+
+		if !canHaveChildren(parent) {
+			return root, errors.New("parent can't have children")
+		}
+
+		insertBefore(parent, Clone(edit.Node, true), reference)
+
+	case EditDelete:
+		node, err := nodeAtPath(root, edit.Path)
+		if err != nil {
+			return root, err
+		}
+
+		if node == root {
+			return root, errors.New("can't delete the root")
+		}
+		Remove(node)
+
+	case EditMove:
+		node, err := nodeAtPath(root, edit.Path)
+		if err != nil {
+			return root, err
+		}
+
+		if node == root || len(edit.To) != len(edit.Path) {
+			return root, errors.New("invalid destination")
+		}
+
+		parent, err := nodeAtPath(root, edit.To[:len(edit.To)-1])
+		if err != nil || parent != node.Parent {
+			return root, errors.New("invalid destination")
+		}
+
+		Remove(node)
+		reference, err := childAtIndex(parent, edit.To[len(edit.To)-1], true)
+		if err != nil {
+			return root, err
+		}
+		insertBefore(parent, node, reference)
+
+	case EditReplace:
+		if edit.Node == nil {
+			return root, errors.New("missing node")
+		}
+
+		node, err := nodeAtPath(root, edit.Path)
+		if err != nil {
+			return root, err
+		}
+
+		replacement := Clone(edit.Node, true)
+		if node == root {
+			return replacement, nil
+		}
+		ReplaceChild(node.Parent, replacement, node)
+
+	case EditText:
+		node, err := nodeAtPath(root, edit.Path)
+		if err != nil {
+			return root, err
+		}
+
+		if node.Type == html.ElementNode || node.Type == html.DocumentNode {
+			return root, errors.New("node doesn't have text")
+		}
+		node.Data = edit.NewValue
+
+	case EditSetAttribute, EditRemoveAttribute:
+		node, err := nodeAtPath(root, edit.Path)
+		if err != nil {
+			return root, err
+		}
+
+		if node.Type != html.ElementNode && node.Type != html.DoctypeNode {
+			return root, errors.New("node doesn't have attribute")
+		}
+
+		idx := -1
+		for i, attr := range node.Attr {
+			if attr.Namespace == edit.Namespace && attr.Key == edit.Key {
+				idx = i
+				break
+			}
+		}
+
+		switch {
+		case edit.Type == EditRemoveAttribute && idx >= 0:
+			node.Attr = append(node.Attr[:idx], node.Attr[idx+1:]...)
+		case edit.Type == EditSetAttribute && idx >= 0:
+			node.Attr[idx].Val = edit.NewValue
+		case edit.Type == EditSetAttribute:
+			node.Attr = append(node.Attr, html.Attribute{
+				Namespace: edit.Namespace,
+				Key:       edit.Key,
+				Val:       edit.NewValue,
+			})
+		}
+
+	default:
+		return root, errors.New("unknown edit type")
+	}
+
+	return root, nil
+}
+
+// nodeAtPath returns the node at the path from the root.
+func nodeAtPath(root *html.Node, path []int) (*html.Node, error) {
+	node := root
+	for _, idx := range path {
+		child, err := childAtIndex(node, idx, false)
+		if err != nil {
+			return nil, err
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// childAtIndex returns the child node at the index. If allowEnd is true, the
+// index may be the number of children, in which case nil is returned.
+func childAtIndex(node *html.Node, idx int, allowEnd bool) (*html.Node, error) {
+	child := node.FirstChild
+	for i := 0; i < idx && child != nil; i++ {
+		child = child.NextSibling
+	}
+
+	if idx < 0 || (child == nil && (!allowEnd || idx != len(ChildNodes(node)))) {
+		return nil, errors.New("path doesn't exist")
+	}
+	return child, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so
+// the edit type is encoded by its name in JSON.
+func (t EditType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(editTypeNames) {
+		return nil, fmt.Errorf("dom: unknown edit type %d", int(t))
+	}
+	return []byte(editTypeNames[t]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *EditType) UnmarshalText(text []byte) error {
+	for i, name := range editTypeNames {
+		if name == string(text) {
+			*t = EditType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("dom: unknown edit type %q", text)
+}
+
+// jsonEdit is the JSON form of Edit.
+type jsonEdit struct {
+	Type      EditType  `json:"op"`
+	Path      []int     `json:"path"`
+	To        []int     `json:"to,omitempty"`
+	Node      *jsonNode `json:"node,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	OldValue  string    `json:"oldValue,omitempty"`
+	NewValue  string    `json:"newValue,omitempty"`
+}
+
+// jsonNode is the JSON form of html.Node and its descendants.
+type jsonNode struct {
+	Type      string          `json:"type"`
+	Data      string          `json:"data,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Attr      []jsonAttribute `json:"attr,omitempty"`
+	Children  []*jsonNode     `json:"children,omitempty"`
+}
+
+type jsonAttribute struct {
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+	Val       string `json:"value"`
+}
+
+var jsonNodeTypes = map[html.NodeType]string{
+	html.TextNode:     "text",
+	html.DocumentNode: "document",
+	html.ElementNode:  "element",
+	html.CommentNode:  "comment",
+	html.DoctypeNode:  "doctype",
+}
+
+// MarshalJSON implements json.Marshaler, so the edits can be stored as a
+// patch. Node is only encoded for EditInsert and EditReplace, since the
+// other edits only need its path to be applied.
+func (e Edit) MarshalJSON() ([]byte, error) {
+	je := jsonEdit{
+		Type:      e.Type,
+		Path:      e.Path,
+		To:        e.To,
+		Namespace: e.Namespace,
+		Key:       e.Key,
+		OldValue:  e.OldValue,
+		NewValue:  e.NewValue,
+	}
+
+	if je.Path == nil {
+		je.Path = []int{}
+	}
+
+	if e.Node != nil && (e.Type == EditInsert || e.Type == EditReplace) {
+		var err error
+		if je.Node, err = encodeJSONNode(e.Node); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(je)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *Edit) UnmarshalJSON(data []byte) error {
+	var je jsonEdit
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+
+	*e = Edit{
+		Type:      je.Type,
+		Path:      je.Path,
+		To:        je.To,
+		Namespace: je.Namespace,
+		Key:       je.Key,
+		OldValue:  je.OldValue,
+		NewValue:  je.NewValue,
+	}
+
+	if je.Node != nil {
+		var err error
+		if e.Node, err = decodeJSONNode(je.Node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeJSONNode(node *html.Node) (*jsonNode, error) {
+	nodeType, known := jsonNodeTypes[node.Type]
+	if !known {
+		return nil, fmt.Errorf("dom: can't encode node with type %d", node.Type)
+	}
+
+	jn := &jsonNode{
+		Type:      nodeType,
+		Data:      node.Data,
+		Namespace: node.Namespace,
+	}
+
+	for _, attr := range node.Attr {
+		jn.Attr = append(jn.Attr, jsonAttribute{Namespace: attr.Namespace, Key: attr.Key, Val: attr.Val})
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		jsonChild, err := encodeJSONNode(child)
+		if err != nil {
+			return nil, err
+		}
+		jn.Children = append(jn.Children, jsonChild)
+	}
+
+	return jn, nil
+}
+
+func decodeJSONNode(jn *jsonNode) (*html.Node, error) {
+	node := &html.Node{
+		Data:      jn.Data,
+		Namespace: jn.Namespace,
+	}
+
+	known := false
+	for nodeType, name := range jsonNodeTypes {
+		if name == jn.Type {
+			node.Type, known = nodeType, true
+			break
+		}
+	}
+
+	if !known {
+		return nil, fmt.Errorf("dom: unknown node type %q", jn.Type)
+	}
+
+	if node.Type == html.ElementNode {
+		node.DataAtom = atom.Lookup([]byte(node.Data))
+	}
+
+	for _, attr := range jn.Attr {
+		node.Attr = append(node.Attr, html.Attribute{Namespace: attr.Namespace, Key: attr.Key, Val: attr.Val})
+	}
+
+	for _, jsonChild := range jn.Children {
+		child, err := decodeJSONNode(jsonChild)
+		if err != nil {
+			return nil, err
+		}
+		node.AppendChild(child)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,234 @@
+package dom_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+var randomTags = []string{"div", "p", "span", "ul", "li", "b", "a"}
+
+var randomWords = []string{"alpha", "beta", "gamma", " ", "delta", "\n"}
+
+func randomNode(rnd *rand.Rand, depth int) *html.Node {
+	switch n := rnd.Intn(10); {
+	case n < 3 || depth > 3:
+		return dom.CreateTextNode(randomWords[rnd.Intn(len(randomWords))])
+	case n < 4:
+		return dom.CreateComment(randomWords[rnd.Intn(len(randomWords))])
+	default:
+		node := dom.CreateElement(randomTags[rnd.Intn(len(randomTags))])
+		randomizeAttributes(rnd, node)
+		for i := rnd.Intn(4); i > 0; i-- {
+			dom.AppendChild(node, randomNode(rnd, depth+1))
+		}
+		return node
+	}
+}
+
+func randomizeAttributes(rnd *rand.Rand, node *html.Node) {
+	for _, name := range []string{"id", "class", "title"} {
+		switch rnd.Intn(3) {
+		case 0:
+			dom.SetAttribute(node, name, randomWords[rnd.Intn(3)])
+		case 1:
+			dom.RemoveAttribute(node, name)
+		}
+	}
+
+	if rnd.Intn(5) == 0 {
+		dom.SetAttributeNS(node, dom.XLinkNamespace, "xlink:href", "#x")
+	}
+}
+
+// mutateTree randomly edits the descendants of node.
+func mutateTree(rnd *rand.Rand, root *html.Node) {
+	var nodes []*html.Node
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			nodes = append(nodes, child)
+			collect(child)
+		}
+	}
+	collect(root)
+
+	for _, node := range nodes {
+		if node.Parent == nil || rnd.Intn(4) != 0 {
+			continue
+		}
+
+		switch rnd.Intn(6) {
+		case 0:
+			dom.Remove(node)
+		case 1:
+			dom.Before(node, randomNode(rnd, 2))
+		case 2:
+			if sibling := node.Parent.LastChild; sibling != node {
+				dom.After(sibling, node)
+			}
+		case 3:
+			dom.Prepend(node.Parent, node)
+		case 4:
+			if node.Type == html.ElementNode {
+				randomizeAttributes(rnd, node)
+			} else {
+				node.Data = randomWords[rnd.Intn(len(randomWords))]
+			}
+		case 5:
+			if node.Type == html.ElementNode {
+				dom.AppendChild(node, randomNode(rnd, 2))
+			}
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(47))
+	for i := 0; i < 500; i++ {
+		a := dom.CreateElement("body")
+		for j := rnd.Intn(6); j > 0; j-- {
+			dom.AppendChild(a, randomNode(rnd, 0))
+		}
+
+		var b *html.Node
+		if i%5 == 0 {
+			b = dom.CreateElement("body")
+			for j := rnd.Intn(6); j > 0; j-- {
+				dom.AppendChild(b, randomNode(rnd, 0))
+			}
+		} else {
+			b = dom.Clone(a, true)
+			mutateTree(rnd, b)
+		}
+
+		edits := dom.Diff(a, b)
+		patch, err := json.Marshal(edits)
+		if err != nil {
+			t.Fatalf("ApplyPatch(), failed to encode patch: %v", err)
+		}
+
+		var decoded []dom.Edit
+		if err := json.Unmarshal(patch, &decoded); err != nil {
+			t.Fatalf("ApplyPatch(), failed to decode patch: %v", err)
+		}
+
+		for _, edits := range [][]dom.Edit{edits, decoded} {
+			root, err := dom.ApplyPatch(dom.Clone(a, true), edits)
+			if err != nil {
+				t.Fatalf("ApplyPatch() error = %v\na: %s\nb: %s\npatch: %s", err, dom.OuterHTML(a), dom.OuterHTML(b), patch)
+			}
+
+			if err := dom.CompareNodes(root, b, dom.EqualOptions{}); err != nil {
+				t.Fatalf("ApplyPatch() %v\na: %s\nb: %s\npatch: %s", err, dom.OuterHTML(a), dom.OuterHTML(b), patch)
+			}
+		}
+	}
+}
+
+func TestApplyPatchDocument(t *testing.T) {
+	a, err := dom.FastParse(strings.NewReader(`<!DOCTYPE html><title>A</title><ul><li>1<li>2<li>3</ul><p id=x>old`))
+	if err != nil {
+		t.Fatalf("ApplyPatch(), failed to parse: %v", err)
+	}
+
+	b, err := dom.FastParse(strings.NewReader(`<!DOCTYPE html><title>B</title><p id=x class=y>new<ul><li>3<li>1<li>2</ul>` +
+		`<svg><a xlink:href="#z"/></svg>`))
+	if err != nil {
+		t.Fatalf("ApplyPatch(), failed to parse: %v", err)
+	}
+
+	root, err := dom.ApplyPatch(a, dom.Diff(a, b))
+	if err != nil {
+		t.Fatalf("ApplyPatch() error = %v", err)
+	}
+
+	if root != a {
+		t.Errorf("ApplyPatch() returns a new root")
+	}
+
+	if got, want := dom.OuterHTML(root), dom.OuterHTML(b); got != want {
+		t.Errorf("ApplyPatch() = %q, want %q", got, want)
+	}
+
+	// Replaced root
+	p := dom.CreateElement("p")
+	root, err = dom.ApplyPatch(p, dom.Diff(p, dom.CreateElement("div")))
+	if err != nil {
+		t.Fatalf("ApplyPatch() error = %v", err)
+	}
+
+	if got := dom.OuterHTML(root); got != "<div></div>" {
+		t.Errorf("ApplyPatch() = %q, want %q", got, "<div></div>")
+	}
+}
+
+func TestEditJSON(t *testing.T) {
+	a, err := dom.FastParse(strings.NewReader(`<p class="a">x</p>`))
+	if err != nil {
+		t.Fatalf("MarshalJSON(), failed to parse: %v", err)
+	}
+
+	b, err := dom.FastParse(strings.NewReader(`<p>y</p><br>`))
+	if err != nil {
+		t.Fatalf("MarshalJSON(), failed to parse: %v", err)
+	}
+
+	got, err := json.Marshal(dom.Diff(a, b))
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+
+	want := `[{"op":"insert","path":[0,1,1],"node":{"type":"element","data":"br"}},` +
+		`{"op":"remove-attribute","path":[0,1,0],"key":"class","oldValue":"a"},` +
+		`{"op":"text","path":[0,1,0,0],"oldValue":"x","newValue":"y"}]`
+	if string(got) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", got, want)
+	}
+
+	var edits []dom.Edit
+	if err := json.Unmarshal([]byte(`[{"op":"rename","path":[]}]`), &edits); err == nil {
+		t.Errorf("UnmarshalJSON() with unknown op returns no error")
+	}
+}
+
+func TestApplyPatchInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []dom.Edit
+	}{{
+		name:  "missing path",
+		edits: []dom.Edit{{Type: dom.EditDelete, Path: []int{0, 5}}},
+	}, {
+		name:  "delete root",
+		edits: []dom.Edit{{Type: dom.EditDelete, Path: []int{}}},
+	}, {
+		name:  "insert out of range",
+		edits: []dom.Edit{{Type: dom.EditInsert, Path: []int{0, 3}, Node: dom.CreateElement("p")}},
+	}, {
+		name:  "text of element",
+		edits: []dom.Edit{{Type: dom.EditText, Path: []int{0}, NewValue: "x"}},
+	}, {
+		name:  "move to other parent",
+		edits: []dom.Edit{{Type: dom.EditMove, Path: []int{0, 0}, To: []int{1}}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := dom.CreateElement("div")
+			p := dom.CreateElement("p")
+			dom.AppendChild(root, p)
+			dom.AppendChild(p, dom.CreateTextNode("a"))
+
+			_, err := dom.ApplyPatch(root, tt.edits)
+			if !errors.Is(err, dom.ErrInvalidPatch) {
+				t.Errorf("ApplyPatch() error = %v, want %v", err, dom.ErrInvalidPatch)
+			}
+		})
+	}
+}
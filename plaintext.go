@@ -0,0 +1,561 @@
+package dom
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/width"
+)
+
+// plainTextRuleWidth is the width of horizontal rule when wrapping is disabled.
+const plainTextRuleWidth = 40
+
+// PlainTextOptions is the options for rendering HTML as plain text.
+type PlainTextOptions struct {
+	// Width is the maximum width of each line, measured in columns where
+	// East Asian wide characters take two columns. Text is wrapped at the
+	// space, or between the East Asian characters. Zero disables wrapping.
+	Width int
+
+	// BaseURL is the URL that used to resolve the relative URL of links
+	// in the footnotes. If it's empty, the URL is kept as it is.
+	BaseURL string
+
+	// Cascade is used to find the hidden and block elements, e.g. from the
+	// <style> elements. If it's nil, only the inline style is used.
+	Cascade *Cascade
+}
+
+// ToPlainText renders the node and its descendants as plain text that laid
+// out for reading, e.g. in email or terminal. Like InnerText, the hidden
+// elements are excluded and the whitespace is collapsed. Besides that, the
+// text is wrapped to the specified width, list items are prefixed with their
+// bullet or number, block quotes are indented with "> ", tables are drawn
+// using ASCII characters, and the URL of links are collected as numbered
+// footnotes at the end of the text.
+func ToPlainText(node *html.Node, opts PlainTextOptions) string {
+	if node == nil {
+		return ""
+	}
+
+	r := plainTextRenderer{
+		cascade: cascadeFor(opts.Cascade),
+	}
+
+	if opts.BaseURL != "" {
+		if baseURL, err := url.Parse(opts.BaseURL); err == nil {
+			r.baseURL = baseURL
+		}
+	}
+
+	blocks := r.renderNodes([]*html.Node{node}, opts.Width)
+	if len(r.links) > 0 {
+		footnotes := make([]string, len(r.links))
+		for i, link := range r.links {
+			footnotes[i] = fmt.Sprintf("[%d] %s", i+1, link)
+		}
+		blocks = append(blocks, strings.Join(footnotes, "\n"))
+	}
+
+	if len(blocks) == 0 {
+		return ""
+	}
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+type plainTextRenderer struct {
+	cascade *Cascade
+	baseURL *url.URL
+	links   []string
+}
+
+// renderNodes renders the nodes into blocks of text. Consecutive
+// inline nodes are grouped into a paragraph.
+func (r *plainTextRenderer) renderNodes(nodes []*html.Node, lineWidth int) []string {
+	var blocks []string
+	var items []textItem
+
+	flushParagraph := func() {
+		if text := joinTextItems(items); text != "" {
+			blocks = append(blocks, wrapText(text, lineWidth))
+		}
+		items = nil
+	}
+
+	for _, node := range nodes {
+		switch {
+		case node.Type == html.DocumentNode:
+			flushParagraph()
+			blocks = append(blocks, r.renderChildren(node, lineWidth)...)
+		case node.Type == html.ElementNode && isBlockLevelDisplay(r.cascade.ComputedStyle(node).Display):
+			flushParagraph()
+			blocks = append(blocks, r.renderBlock(node, lineWidth)...)
+		default:
+			items = append(items, r.collectInline(node)...)
+		}
+	}
+
+	flushParagraph()
+	return blocks
+}
+
+func (r *plainTextRenderer) renderChildren(node *html.Node, lineWidth int) []string {
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, child)
+	}
+	return r.renderNodes(children, lineWidth)
+}
+
+func (r *plainTextRenderer) renderBlock(node *html.Node, lineWidth int) []string {
+	style := r.cascade.ComputedStyle(node)
+
+	var text string
+	switch {
+	case isHTMLElement(node, "ul"), isHTMLElement(node, "ol"):
+		text = r.renderList(node, lineWidth)
+
+	case isHTMLElement(node, "blockquote"):
+		blocks := r.renderChildren(node, reduceWidth(lineWidth, 2))
+		if len(blocks) == 0 {
+			return nil
+		}
+
+		lines := strings.Split(strings.Join(blocks, "\n\n"), "\n")
+		for i, line := range lines {
+			if line == "" {
+				lines[i] = ">"
+			} else {
+				lines[i] = "> " + line
+			}
+		}
+		text = strings.Join(lines, "\n")
+
+	case style.Display == "table":
+		text = r.renderTable(node)
+
+	case isHTMLElement(node, "hr"):
+		ruleWidth := lineWidth
+		if ruleWidth <= 0 {
+			ruleWidth = plainTextRuleWidth
+		}
+		text = strings.Repeat("-", ruleWidth)
+
+	case isHTMLElement(node, "h1"), isHTMLElement(node, "h2"):
+		text = wrapText(r.inlineText(node), lineWidth)
+		if text == "" {
+			return nil
+		}
+
+		underline := "="
+		if node.Data == "h2" {
+			underline = "-"
+		}
+
+		maxWidth := 0
+		for _, line := range strings.Split(text, "\n") {
+			if w := textWidth(line); w > maxWidth {
+				maxWidth = w
+			}
+		}
+		text += "\n" + strings.Repeat(underline, maxWidth)
+
+	case style.WhiteSpace == "pre" || style.WhiteSpace == "nowrap":
+		text = r.inlineText(node)
+
+	default:
+		return r.renderChildren(node, lineWidth)
+	}
+
+	if text == "" {
+		return nil
+	}
+	return []string{text}
+}
+
+// renderList renders the list items, prefixed with bullet for <ul> or
+// number for <ol>. The content of each item is indented to the width
+// of its marker.
+func (r *plainTextRenderer) renderList(list *html.Node, lineWidth int) string {
+	number := 1
+	if list.Data == "ol" {
+		if start, err := strconv.Atoi(GetAttribute(list, "start")); err == nil {
+			number = start
+		}
+	}
+
+	var items []string
+	for child := list.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && r.cascade.ComputedStyle(child).Display == "none" {
+			continue
+		}
+
+		if child.Type == html.TextNode && strings.TrimSpace(child.Data) == "" {
+			continue
+		}
+
+		marker := "* "
+		if list.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+
+		var blocks []string
+		if isHTMLElement(child, "li") {
+			blocks = r.renderChildren(child, reduceWidth(lineWidth, len(marker)))
+		} else {
+			blocks = r.renderNodes([]*html.Node{child}, reduceWidth(lineWidth, len(marker)))
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(strings.Join(blocks, "\n"), "\n")
+		for i := range lines {
+			switch {
+			case i == 0:
+				lines[i] = strings.TrimRight(marker+lines[i], " ")
+			case lines[i] != "":
+				lines[i] = indent + lines[i]
+			}
+		}
+
+		items = append(items, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// renderTable draws the table using ASCII characters. Cells are not
+// wrapped, but their line breaks are kept. If the first row only
+// contains header cells, it's separated using double line.
+func (r *plainTextRenderer) renderTable(table *html.Node) string {
+	var rows [][][]string
+	var colWidths []int
+	hasHeader := false
+
+	for _, row := range tableRows(table) {
+		if r.cascade.ComputedStyle(row).Display == "none" {
+			continue
+		}
+
+		var cells [][]string
+		allHeader := true
+		for _, cell := range tableCells(row) {
+			if r.cascade.ComputedStyle(cell).Display == "none" {
+				continue
+			}
+
+			if cell.Data != "th" {
+				allHeader = false
+			}
+
+			lines := strings.Split(r.inlineText(cell), "\n")
+			for _, line := range lines {
+				col := len(cells)
+				if col >= len(colWidths) {
+					colWidths = append(colWidths, 0)
+				}
+
+				if lineWidth := textWidth(line); lineWidth > colWidths[col] {
+					colWidths[col] = lineWidth
+				}
+			}
+			cells = append(cells, lines)
+		}
+
+		if len(cells) > 0 {
+			hasHeader = hasHeader || (len(rows) == 0 && allHeader)
+			rows = append(rows, cells)
+		}
+	}
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	border := func(char string) string {
+		var sb strings.Builder
+		sb.WriteString("+")
+		for _, colWidth := range colWidths {
+			sb.WriteString(strings.Repeat(char, colWidth+2) + "+")
+		}
+		return sb.String()
+	}
+
+	lines := []string{border("-")}
+	for i, cells := range rows {
+		nLines := 0
+		for _, cellLines := range cells {
+			if len(cellLines) > nLines {
+				nLines = len(cellLines)
+			}
+		}
+
+		for j := 0; j < nLines; j++ {
+			var sb strings.Builder
+			sb.WriteString("|")
+			for col, colWidth := range colWidths {
+				line := ""
+				if col < len(cells) && j < len(cells[col]) {
+					line = cells[col][j]
+				}
+				sb.WriteString(" " + line + strings.Repeat(" ", colWidth-textWidth(line)) + " |")
+			}
+			lines = append(lines, sb.String())
+		}
+
+		if i == 0 && hasHeader {
+			lines = append(lines, border("="))
+		} else {
+			lines = append(lines, border("-"))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// inlineText returns the text of the node's children, with the
+// whitespace processed like in InnerText.
+func (r *plainTextRenderer) inlineText(node *html.Node) string {
+	var items []textItem
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		items = append(items, r.collectInline(child)...)
+	}
+	return joinTextItems(items)
+}
+
+// collectInline collects the text items of the node, following
+// the visibility rules of InnerText. The link is followed by the
+// number of its footnote.
+func (r *plainTextRenderer) collectInline(node *html.Node) []textItem {
+	style := r.cascade.ComputedStyle(node)
+	switch node.Type {
+	case html.TextNode:
+		if style.Visibility != "visible" {
+			return nil
+		}
+		return []textItem{{text: node.Data, whiteSpace: style.WhiteSpace}}
+	case html.ElementNode:
+		if style.Display == "none" {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	var items []textItem
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		items = append(items, r.collectInline(child)...)
+	}
+
+	if style.Visibility == "visible" {
+		switch {
+		case isHTMLElement(node, "br"):
+			items = append(items, textItem{text: "\n", whiteSpace: "pre"})
+		case isHTMLElement(node, "img"):
+			if alt := strings.TrimSpace(GetAttribute(node, "alt")); alt != "" {
+				items = append(items, textItem{text: "[" + alt + "]", whiteSpace: "normal"})
+			}
+		case isHTMLElement(node, "a") && len(items) > 0:
+			if footnote := r.addLink(GetAttribute(node, "href")); footnote > 0 {
+				items = append(items, textItem{text: fmt.Sprintf("[%d]", footnote), whiteSpace: "pre"})
+			}
+		}
+	}
+
+	if isBlockLevelDisplay(style.Display) {
+		items = append([]textItem{{breakCount: 1}}, items...)
+		items = append(items, textItem{breakCount: 1})
+	}
+
+	return items
+}
+
+// addLink registers the link as footnote, and returns its number. Same
+// URL will share the same number. Returns zero for link that doesn't
+// need footnote, e.g. fragment link or script.
+func (r *plainTextRenderer) addLink(href string) int {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return 0
+	}
+
+	if r.baseURL != nil {
+		if parsedURL, err := url.Parse(href); err == nil {
+			href = r.baseURL.ResolveReference(parsedURL).String()
+		}
+	}
+
+	for i, link := range r.links {
+		if link == href {
+			return i + 1
+		}
+	}
+
+	r.links = append(r.links, href)
+	return len(r.links)
+}
+
+// reduceWidth reduces the line width for indented content.
+func reduceWidth(lineWidth, indent int) int {
+	if lineWidth <= 0 {
+		return lineWidth
+	}
+
+	if lineWidth-indent < 1 {
+		return 1
+	}
+	return lineWidth - indent
+}
+
+// wrapText wraps each line in the text to the specified width.
+func wrapText(text string, lineWidth int) string {
+	if lineWidth <= 0 {
+		return text
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, wrapLine(line, lineWidth)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapSegment is a part of line that can't be broken, unless
+// it's longer than the line width.
+type wrapSegment struct {
+	text        string
+	spaceBefore bool
+}
+
+// wrapLine wraps the line into several lines which width is not
+// more than the line width, using greedy algorithm.
+func wrapLine(line string, lineWidth int) []string {
+	if textWidth(line) <= lineWidth {
+		return []string{line}
+	}
+
+	var lines []string
+	var sb strings.Builder
+	currentWidth := 0
+
+	for _, segment := range splitWrapSegments(line) {
+		spaceWidth := 0
+		if sb.Len() > 0 && segment.spaceBefore {
+			spaceWidth = 1
+		}
+
+		if sb.Len() > 0 && currentWidth+spaceWidth+textWidth(segment.text) > lineWidth {
+			lines = append(lines, sb.String())
+			sb.Reset()
+			currentWidth = 0
+		}
+
+		if sb.Len() > 0 && segment.spaceBefore {
+			sb.WriteByte(' ')
+			currentWidth++
+		}
+
+		// Segment that longer than line width is broken at any character
+		for _, char := range segment.text {
+			charWidth := runeWidth(char)
+			if currentWidth > 0 && currentWidth+charWidth > lineWidth {
+				lines = append(lines, sb.String())
+				sb.Reset()
+				currentWidth = 0
+			}
+
+			sb.WriteRune(char)
+			currentWidth += charWidth
+		}
+	}
+
+	if sb.Len() > 0 {
+		lines = append(lines, sb.String())
+	}
+
+	return lines
+}
+
+// splitWrapSegments splits the line at the break opportunities, i.e.
+// at the spaces, and around East Asian characters.
+func splitWrapSegments(line string) []wrapSegment {
+	var segments []wrapSegment
+	var current strings.Builder
+	spaceBefore := false
+	var prev rune
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, wrapSegment{
+				text:        current.String(),
+				spaceBefore: spaceBefore,
+			})
+			current.Reset()
+			spaceBefore = false
+		}
+	}
+
+	for _, char := range line {
+		if char == ' ' {
+			flush()
+			spaceBefore = true
+			continue
+		}
+
+		if current.Len() > 0 && canBreakBetween(prev, char) {
+			flush()
+		}
+
+		current.WriteRune(char)
+		prev = char
+	}
+
+	flush()
+	return segments
+}
+
+// canBreakBetween check whether the line can be broken between two
+// characters without space. It's allowed around East Asian characters,
+// except after opening punctuation and before closing punctuation.
+func canBreakBetween(before, after rune) bool {
+	switch {
+	case unicode.Is(unicode.Ps, before):
+		return false
+	case isEastAsianWide(after):
+		return !unicode.IsPunct(after) || unicode.Is(unicode.Ps, after)
+	case isEastAsianWide(before):
+		return unicode.IsLetter(after) || unicode.IsDigit(after)
+	default:
+		return false
+	}
+}
+
+// textWidth returns the width of the text in columns.
+func textWidth(text string) int {
+	textWidth := 0
+	for _, char := range text {
+		textWidth += runeWidth(char)
+	}
+	return textWidth
+}
+
+// runeWidth returns the width of the rune in columns. East Asian wide and
+// fullwidth characters take two columns, while combining mark and format
+// character take nothing.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
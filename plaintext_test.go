@@ -0,0 +1,118 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestToPlainText(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		opts       dom.PlainTextOptions
+		want       string
+	}{{
+		name: "headings and paragraphs",
+		htmlSource: `<h1>Title</h1><h2>Sub   title</h2>
+			<p>First paragraph.</p><p style="display:none">Hidden</p><p>Second<br>line</p>`,
+		want: "Title\n=====\n\nSub title\n---------\n\nFirst paragraph.\n\nSecond\nline\n",
+	}, {
+		name:       "wrap latin text",
+		htmlSource: `<p>The quick brown fox jumps over the lazy dog.</p>`,
+		opts:       dom.PlainTextOptions{Width: 16},
+		want:       "The quick brown\nfox jumps over\nthe lazy dog.\n",
+	}, {
+		name:       "wrap long word",
+		htmlSource: `<p>abcdefghij klm</p>`,
+		opts:       dom.PlainTextOptions{Width: 4},
+		want:       "abcd\nefgh\nij\nklm\n",
+	}, {
+		name:       "wrap east asian text",
+		htmlSource: `<p>日本語の文章は、単語の間に空白がありません。</p>`,
+		opts:       dom.PlainTextOptions{Width: 10},
+		want:       "日本語の文\n章は、単語\nの間に空白\nがありませ\nん。\n",
+	}, {
+		name: "nested lists",
+		htmlSource: `<ul><li>First</li><li>Second<ol start="9"><li>Nine</li><li>Ten</li></ol></li>
+			<li hidden>Hidden</li></ul>`,
+		want: "* First\n* Second\n  9. Nine\n  10. Ten\n",
+	}, {
+		name:       "wrap list item",
+		htmlSource: `<ol><li>one two three four</li></ol>`,
+		opts:       dom.PlainTextOptions{Width: 12},
+		want:       "1. one two\n   three\n   four\n",
+	}, {
+		name:       "block quote",
+		htmlSource: `<blockquote><p>Quoted</p><blockquote>Nested</blockquote></blockquote>`,
+		want:       "> Quoted\n>\n> > Nested\n",
+	}, {
+		name: "preformatted",
+		htmlSource: `<pre>func main() {
+	println("hi")
+}</pre>`,
+		opts: dom.PlainTextOptions{Width: 8},
+		want: "func main() {\n\tprintln(\"hi\")\n}\n",
+	}, {
+		name: "table",
+		htmlSource: `<table><tr><th>Name</th><th>Lang</th></tr>
+			<tr><td>Go</td><td>英語<br>Second</td></tr><tr><td>Python</td><td>x</td></tr></table>`,
+		want: "+--------+--------+\n" +
+			"| Name   | Lang   |\n" +
+			"+========+========+\n" +
+			"| Go     | 英語   |\n" +
+			"|        | Second |\n" +
+			"+--------+--------+\n" +
+			"| Python | x      |\n" +
+			"+--------+--------+\n",
+	}, {
+		name: "table with hidden first row",
+		htmlSource: `<table><tr hidden><td>Hidden</td></tr>
+			<tr><th>Name</th></tr><tr><td>Go</td></tr></table>`,
+		want: "+------+\n" +
+			"| Name |\n" +
+			"+======+\n" +
+			"| Go   |\n" +
+			"+------+\n",
+	}, {
+		name: "link footnotes",
+		htmlSource: `<p>See <a href="/docs">the docs</a>, <a href="#top">top</a>,
+			<a href="https://example.org/">example</a> and <a href="docs/../docs">docs again</a>.</p>`,
+		opts: dom.PlainTextOptions{BaseURL: "https://example.com/"},
+		want: "See the docs[1], top, example[2] and docs again[1].\n\n" +
+			"[1] https://example.com/docs\n[2] https://example.org/\n",
+	}, {
+		name:       "horizontal rule and image",
+		htmlSource: `<p><img src="a.png" alt="Logo"> text</p><hr>`,
+		opts:       dom.PlainTextOptions{Width: 10},
+		want:       "[Logo]\ntext\n\n----------\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.Parse(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("ToPlainText(), failed to parse: %v", err)
+			}
+
+			if got := dom.ToPlainText(doc, tt.opts); got != tt.want {
+				t.Errorf("ToPlainText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	t.Run("custom cascade", func(t *testing.T) {
+		doc, err := dom.Parse(strings.NewReader(`<link rel="stylesheet" href="main.css"><p>Hello</p><p class="ad">Ad</p>`))
+		if err != nil {
+			t.Fatalf("ToPlainText(), failed to parse: %v", err)
+		}
+
+		cascade := dom.NewCascade(doc, dom.CascadeOptions{
+			ResolveStylesheet: func(string) (string, bool) { return ".ad { display: none }", true },
+		})
+
+		if got, want := dom.ToPlainText(doc, dom.PlainTextOptions{Cascade: cascade}), "Hello\n"; got != want {
+			t.Errorf("ToPlainText() = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package dom
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	xunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Location is a location in the source of document.
+type Location struct {
+	// Offset is the byte offset in the original input,
+	// before it's converted into UTF-8.
+	Offset int
+
+	// Line and Column is the 1-based line and column number.
+	// The column is counted in characters, not bytes.
+	Line   int
+	Column int
+}
+
+// Position is the position of a node in the source of document.
+// Start is the location of its first byte, while End is the
+// location right after its last byte.
+type Position struct {
+	Start Location
+	End   Location
+}
+
+// Position returns the position of the node in the source of document, i.e. from
+// the start of its start tag until the end of its end tag. The document must be
+// parsed using TrackPositions option. If the tags of element are implied by the
+// parser, the position of its content is used instead. Returns false if the
+// node doesn't have any source, e.g. it's created after the document is parsed.
+func (d *Document) Position(node *html.Node) (Position, bool) {
+	if d.source == nil {
+		return Position{}, false
+	}
+
+	sn := d.source.nodes[node]
+	if sn == nil || sn.first < 0 {
+		return Position{}, false
+	}
+
+	start := d.source.tokens[sn.first].start
+	if sn.startTag >= 0 {
+		start = d.source.tokens[sn.startTag].start
+	}
+
+	if node.Type == html.DocumentNode {
+		start = 0
+	}
+
+	end := d.source.tokens[sn.last].end
+	return Position{
+		Start: d.source.location(start),
+		End:   d.source.location(end),
+	}, true
+}
+
+// location returns the location of the offset in the source.
+func (m *sourceMap) location(offset int) Location {
+	line := sort.Search(len(m.lineStarts), func(i int) bool {
+		return m.lineStarts[i] > offset
+	})
+
+	lineStart := m.lineStarts[line-1]
+	return Location{
+		Offset: m.originalOffset(offset),
+		Line:   line,
+		Column: utf8.RuneCount(m.source[lineStart:offset]) + 1,
+	}
+}
+
+// originalOffset converts the offset in the source into the original input.
+func (m *sourceMap) originalOffset(offset int) int {
+	if m.offsets == nil {
+		return offset
+	}
+
+	idx := sort.Search(len(m.offsets), func(i int) bool {
+		return m.offsets[i].decoded > offset
+	})
+
+	if idx == 0 {
+		return offset
+	}
+
+	// The offset is always at the start of character, except
+	// at the end of source which is the end of the input
+	so := m.offsets[idx-1]
+	if idx == len(m.offsets) && offset > so.decoded {
+		return m.offsets[len(m.offsets)-1].original + (offset - so.decoded)
+	}
+	return so.original
+}
+
+// decodeSource converts the content into UTF-8, and returns the offset of each
+// character in the converted source and the original content. If the content
+// is already a valid UTF-8, it's returned as it is without any offsets.
+func decodeSource(content []byte, enc encoding.Encoding) ([]byte, []sourceOffset, error) {
+	if enc == xunicode.UTF8 && utf8.Valid(content) {
+		return content, nil, nil
+	}
+
+	// Decode the content one character at a time, so the offset
+	// in the source can be mapped into the original content
+	var source []byte
+	var offsets []sourceOffset
+	decoder := enc.NewDecoder()
+	buffer := make([]byte, 64)
+
+	for src := 0; src < len(content); {
+		end := src + 1
+		for {
+			atEOF := end == len(content)
+			nDst, nSrc, err := decoder.Transform(buffer, content[src:end], atEOF)
+			if nSrc > 0 {
+				offsets = append(offsets, sourceOffset{decoded: len(source), original: src})
+				source = append(source, buffer[:nDst]...)
+				src += nSrc
+				break
+			}
+
+			switch {
+			case atEOF:
+				return nil, nil, errors.New("dom: failed to decode the input")
+			case err == nil, err == transform.ErrShortSrc:
+				end++
+			default:
+				return nil, nil, err
+			}
+		}
+	}
+
+	offsets = append(offsets, sourceOffset{decoded: len(source), original: len(content)})
+	return source, offsets, nil
+}
@@ -0,0 +1,164 @@
+package dom_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+func TestDocumentPosition(t *testing.T) {
+	htmlSource := "<!DOCTYPE html>\n<html>\n<head><title>Tést</title></head>\n" +
+		"<body>\n<p id=\"a\">héllo <b>wörld</b></p>\n<!-- note -->\n<ul><li>one<li>two</ul>\n</body>\n</html>\n"
+
+	doc, err := dom.FastParseDocumentWithOptions(strings.NewReader(htmlSource), dom.ParseOptions{TrackPositions: true})
+	if err != nil {
+		t.Fatalf("Position(), failed to parse: %v", err)
+	}
+
+	location := func(offset int) dom.Location {
+		prefix := htmlSource[:offset]
+		line := strings.Count(prefix, "\n") + 1
+		lineStart := strings.LastIndex(prefix, "\n") + 1
+		return dom.Location{
+			Offset: offset,
+			Line:   line,
+			Column: len([]rune(prefix[lineStart:])) + 1,
+		}
+	}
+
+	comment := doc.Body().FirstChild
+	for comment != nil && comment.Type != html.CommentNode {
+		comment = comment.NextSibling
+	}
+
+	tests := []struct {
+		name string
+		node *html.Node
+		want string
+	}{{
+		name: "element",
+		node: dom.QuerySelector(doc.Node, "p"),
+		want: `<p id="a">héllo <b>wörld</b></p>`,
+	}, {
+		name: "nested element",
+		node: dom.QuerySelector(doc.Node, "b"),
+		want: `<b>wörld</b>`,
+	}, {
+		name: "text",
+		node: dom.QuerySelector(doc.Node, "title").FirstChild,
+		want: `Tést`,
+	}, {
+		name: "comment",
+		node: comment,
+		want: `<!-- note -->`,
+	}, {
+		name: "implied end tag",
+		node: dom.QuerySelector(doc.Node, "li"),
+		want: `<li>one`,
+	}, {
+		name: "head",
+		node: doc.Head(),
+		want: `<head><title>Tést</title></head>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := doc.Position(tt.node)
+			if !ok {
+				t.Fatalf("Position() returns false")
+			}
+
+			start := strings.Index(htmlSource, tt.want)
+			want := dom.Position{Start: location(start), End: location(start + len(tt.want))}
+			if got != want {
+				t.Errorf("Position() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDocumentPositionImplied(t *testing.T) {
+	htmlSource := "<title>x</title>\n<p>text</p>"
+
+	doc, err := dom.FastParseDocumentWithOptions(strings.NewReader(htmlSource), dom.ParseOptions{TrackPositions: true})
+	if err != nil {
+		t.Fatalf("Position(), failed to parse: %v", err)
+	}
+
+	got, ok := doc.Position(doc.Body())
+	if !ok {
+		t.Fatalf("Position() returns false")
+	}
+
+	want := dom.Position{
+		Start: dom.Location{Offset: 17, Line: 2, Column: 1},
+		End:   dom.Location{Offset: 28, Line: 2, Column: 12},
+	}
+	if got != want {
+		t.Errorf("Position() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDocumentPositionEncoded(t *testing.T) {
+	htmlSource := "<html><head><meta charset=\"shift_jis\"><title>日本語のページ</title></head>\n" +
+		"<body><p>これは日本語で書かれたページです。</p><p id=\"b\">文字コード</p></body></html>"
+
+	encoded, _, err := transform.String(japanese.ShiftJIS.NewEncoder(), htmlSource)
+	if err != nil {
+		t.Fatalf("Position(), failed to encode: %v", err)
+	}
+
+	doc, err := dom.ParseDocumentWithOptions(bytes.NewReader([]byte(encoded)), dom.ParseOptions{TrackPositions: true})
+	if err != nil {
+		t.Fatalf("Position(), failed to parse: %v", err)
+	}
+
+	got, ok := doc.Position(dom.QuerySelector(doc.Node, "#b"))
+	if !ok {
+		t.Fatalf("Position() returns false")
+	}
+
+	target := `<p id="b">文字コード</p>`
+	start := strings.Index(encoded, target[:10])
+	end := strings.Index(encoded, "</body>")
+	want := dom.Position{
+		Start: dom.Location{Offset: start, Line: 2, Column: 31},
+		End:   dom.Location{Offset: end, Line: 2, Column: 50},
+	}
+	if got != want {
+		t.Errorf("Position() = %+v, want %+v", got, want)
+	}
+
+	if got := encoded[got.Start.Offset:got.End.Offset]; !strings.HasPrefix(got, `<p id="b">`) {
+		t.Errorf("Position(), got source %q", got)
+	}
+}
+
+func TestDocumentPositionWithoutSource(t *testing.T) {
+	htmlSource := "<p>text</p>"
+
+	doc, err := dom.FastParseDocument(strings.NewReader(htmlSource))
+	if err != nil {
+		t.Fatalf("Position(), failed to parse: %v", err)
+	}
+
+	if _, ok := doc.Position(dom.QuerySelector(doc.Node, "p")); ok {
+		t.Errorf("Position() without TrackPositions returns true")
+	}
+
+	doc, err = dom.FastParseDocumentWithOptions(strings.NewReader(htmlSource), dom.ParseOptions{TrackPositions: true})
+	if err != nil {
+		t.Fatalf("Position(), failed to parse: %v", err)
+	}
+
+	div := dom.CreateElement("div")
+	dom.AppendChild(doc.Body(), div)
+	if _, ok := doc.Position(div); ok {
+		t.Errorf("Position() of new node returns true")
+	}
+}
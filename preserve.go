@@ -0,0 +1,323 @@
+package dom
+
+import (
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+)
+
+// RenderPreserving renders the document while preserving its source as much
+// as possible. It requires the document to be parsed using PreserveSource
+// option, otherwise it works like html.Render. Nodes that haven't been modified
+// since the document is parsed are copied from the original input, so the
+// untouched parts of document are identical byte by byte with the original.
+// Only the modified nodes, i.e. whose data, attributes or child nodes has been
+// changed, and the new nodes are serialized. If the input is not UTF-8, the
+// serialized nodes are encoded into its encoding, with the characters that
+// can't be encoded written as numeric character reference. For modified
+// element, its original start tag is kept if its attributes are not changed,
+// and its original end tag is kept as well. Content that moved by the parser,
+// e.g. <div> that foster-parented out of <table>, is serialized together with
+// the siblings it's moved around once their parent is modified, since their
+// source is interleaved. Note that since the formatting elements that misnested
+// in the source (e.g. <b><i>x</b>y</i>) are reconstructed by the parser,
+// modifying them might not be reproduced correctly.
+func (d *Document) RenderPreserving(w io.Writer) error {
+	if d.source == nil {
+		return html.Render(w, d.Node)
+	}
+
+	out := &renderWriter{w: w}
+	p := preservingRenderer{
+		m:     d.source,
+		rw:    out,
+		out:   out,
+		clean: make(map[*html.Node]bool),
+	}
+
+	if d.source.encoding != nil {
+		encoder := encoding.HTMLEscapeUnsupported(d.source.encoding.NewEncoder())
+		p.rw = &renderWriter{w: writerFunc(func(b []byte) (int, error) {
+			encoded, err := encoder.Bytes(b)
+			if err != nil {
+				return 0, err
+			}
+
+			out.writeString(string(encoded))
+			return len(b), out.err
+		})}
+	}
+
+	p.writeNode(d.Node)
+	if out.err != nil {
+		return out.err
+	}
+	return p.rw.err
+}
+
+type preservingRenderer struct {
+	m     *sourceMap
+	clean map[*html.Node]bool
+
+	// rw is used to write the serialized nodes, while out is used to copy
+	// the original input. They are the same if the input is UTF-8.
+	rw  *renderWriter
+	out *renderWriter
+}
+
+func (p *preservingRenderer) writeNode(node *html.Node) {
+	sn := p.m.nodes[node]
+	switch {
+	case node.Type == html.DocumentNode && p.isClean(node):
+		if p.m.encoding != nil {
+			p.out.writeString(string(p.m.input))
+		} else {
+			p.out.writeString(string(p.m.source))
+		}
+
+	case p.canCopy(node, sn):
+		p.writeTokens(sn.first, sn.last+1, false)
+		if node.Type == html.ElementNode && !p.hasEndTag(node, sn) && !p.isClosedAsBefore(node) {
+			p.rw.writeString(endTag(node))
+		}
+
+	case node.Type == html.DocumentNode:
+		pos := p.writeChildren(node, 0)
+		p.writeTokens(pos, len(p.m.tokens), true)
+
+	case node.Type == html.ElementNode:
+		p.writeElement(node, sn)
+
+	case node.Type == html.TextNode:
+		if parent := node.Parent; parent != nil && parent.FirstChild == node && needsLeadingNewline(parent) {
+			p.rw.writeString("\n")
+		}
+		p.rw.writeVerbatim(node)
+
+	default:
+		p.rw.writeVerbatim(node)
+	}
+}
+
+func (p *preservingRenderer) writeElement(node *html.Node, sn *sourceNode) {
+	tagChanged := sn == nil || node.Data != sn.data || !equalAttributes(node.Attr, sn.attr)
+	childrenChanged := sn == nil || !equalNodes(ChildNodes(node), sn.children)
+	selfClosed := p.isSelfClosed(node, sn)
+
+	pos, serialized := -1, false
+	switch {
+	case tagChanged || (selfClosed && node.FirstChild != nil):
+		serialized, selfClosed = true, false
+	case sn.startTag >= 0:
+		p.writeTokens(sn.startTag, sn.startTag+1, false)
+	default:
+		serialized = !p.keepsImpliedStartTag(node, sn, childrenChanged)
+	}
+
+	if serialized {
+		p.rw.writeString(startTag(node))
+	}
+
+	if sn != nil && sn.startTag >= 0 {
+		pos = sn.startTag + 1
+	}
+
+	if IsVoidElement(node) || selfClosed {
+		if node.FirstChild != nil {
+			p.rw.setError(errors.New("dom: void element <" + node.Data + "> has child nodes"))
+		}
+		return
+	}
+
+	pos = p.writeChildren(node, pos)
+	switch {
+	case sn != nil && sn.endTag >= 0:
+		if pos >= 0 && pos <= sn.endTag {
+			p.writeTokens(pos, sn.endTag, true)
+		}
+
+		if node.Data != sn.data {
+			p.rw.writeString(endTag(node))
+		} else {
+			p.writeTokens(sn.endTag, sn.endTag+1, false)
+		}
+
+	case serialized && node.Namespace != "", !p.isClosedAsBefore(node):
+		p.rw.writeString(endTag(node))
+	}
+}
+
+// writeChildren writes the child nodes of the node. The ignored tokens between
+// the children, e.g. whitespace that ignored by the parser, are copied as well.
+// The pos is the index of token after the last written token, or -1 if it's
+// unknown. Returns the index of token after the last child.
+func (p *preservingRenderer) writeChildren(node *html.Node, pos int) int {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		sn := p.m.nodes[child]
+		if sn != nil && sn.run != nil {
+			child, pos = p.writeReordered(child, pos)
+			continue
+		}
+
+		hasSource := sn != nil && sn.first >= 0
+		if hasSource && pos >= 0 && pos <= sn.first {
+			p.writeTokens(pos, sn.first, true)
+		}
+
+		p.writeNode(child)
+		if hasSource && sn.last+1 > pos {
+			pos = sn.last + 1
+		}
+	}
+	return pos
+}
+
+// writeReordered serializes the node and its next siblings that reordered
+// together by the parser, since their source can't be copied in order. Returns
+// the last written sibling and the index of token after their source.
+func (p *preservingRenderer) writeReordered(node *html.Node, pos int) (*html.Node, int) {
+	run := p.m.nodes[node].run
+	if pos >= 0 && pos <= run.first {
+		p.writeTokens(pos, run.first, true)
+	}
+
+	for {
+		p.rw.writeVerbatim(node)
+
+		next := node.NextSibling
+		if next == nil || p.m.nodes[next] == nil || p.m.nodes[next].run != run {
+			break
+		}
+		node = next
+	}
+
+	if run.last+1 > pos {
+		pos = run.last + 1
+	}
+	return node, pos
+}
+
+// writeTokens copies the source of tokens in range [start, end). If
+// ignoredOnly is true, only the tokens that ignored by the parser are copied.
+func (p *preservingRenderer) writeTokens(start, end int, ignoredOnly bool) {
+	for _, token := range p.m.tokens[start:end] {
+		if !ignoredOnly || (token.owner == nil && isIgnoredToken(token)) {
+			p.out.writeString(string(p.sourceBytes(token.start, token.end)))
+		}
+	}
+}
+
+// sourceBytes returns the source in range [start, end) as it's written in
+// the original input, if the document is rendered in its original encoding.
+func (p *preservingRenderer) sourceBytes(start, end int) []byte {
+	if p.m.encoding == nil {
+		return p.m.source[start:end]
+	}
+	return p.m.input[p.m.originalOffset(start):p.m.originalOffset(end)]
+}
+
+// canCopy check whether the node can be reproduced by copying its source.
+func (p *preservingRenderer) canCopy(node *html.Node, sn *sourceNode) bool {
+	if sn == nil || sn.first < 0 || !sn.selfContained || !p.isClean(node) {
+		return false
+	}
+
+	return node.Type != html.ElementNode || sn.startTag >= 0 || p.keepsImpliedStartTag(node, sn, false)
+}
+
+// keepsImpliedStartTag check whether the start tag of element that implied in
+// the source can be kept implied, i.e. it will be implied by the parser again.
+// For <html>, <head> and <body> it's implied by their first child, so it must
+// not be changed. For the other elements, the whole content must not be changed.
+func (p *preservingRenderer) keepsImpliedStartTag(node *html.Node, sn *sourceNode, childrenChanged bool) bool {
+	if !canImplyStartTag(node, sn) {
+		return false
+	}
+
+	switch {
+	case isStructuralElement(node), isHTMLElement(node, "body"):
+		return !childrenChanged || (len(sn.children) > 0 && node.FirstChild == sn.children[0])
+	case isHTMLElement(node, "p"):
+		return node.FirstChild == nil
+	default:
+		return !childrenChanged
+	}
+}
+
+// hasEndTag check whether the element is closed in its source,
+// either by its end tag, or because it's void or self-closed.
+func (p *preservingRenderer) hasEndTag(node *html.Node, sn *sourceNode) bool {
+	return sn.endTag >= 0 || IsVoidElement(node) || p.isSelfClosed(node, sn)
+}
+
+// isSelfClosed check whether the element is a self-closed foreign element in its source.
+func (p *preservingRenderer) isSelfClosed(node *html.Node, sn *sourceNode) bool {
+	return sn != nil && sn.startTag >= 0 && node.Namespace != "" &&
+		p.m.tokens[sn.startTag].Type == html.SelfClosingTagToken
+}
+
+// isClosedAsBefore check whether the element whose end tag is implied will be
+// closed by the parser the same way as in the source, i.e. its next sibling is
+// not changed. If it's the last child, it's closed by the end of its parent.
+func (p *preservingRenderer) isClosedAsBefore(node *html.Node) bool {
+	sn := p.m.nodes[node]
+	if sn == nil || node.Parent != sn.parent || node.NextSibling != sn.next {
+		return false
+	}
+
+	next := node.NextSibling
+	if next == nil {
+		return true
+	}
+
+	nextSource := p.m.nodes[next]
+	return nextSource != nil && next.Data == nextSource.data
+}
+
+// isClean check whether the node and its descendants
+// haven't been modified since the document is parsed.
+func (p *preservingRenderer) isClean(node *html.Node) bool {
+	if clean, checked := p.clean[node]; checked {
+		return clean
+	}
+
+	sn := p.m.nodes[node]
+	clean := sn != nil && node.Data == sn.data &&
+		equalAttributes(node.Attr, sn.attr) &&
+		equalNodes(ChildNodes(node), sn.children)
+
+	for child := node.FirstChild; clean && child != nil; child = child.NextSibling {
+		clean = p.isClean(child)
+	}
+
+	p.clean[node] = clean
+	return clean
+}
+
+func equalAttributes(a, b []html.Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalNodes(a, b []*html.Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,255 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+var preservingSources = []string{
+	"<!DOCTYPE html>\n<HTML lang=en>\n<head>\n<title>T</title>\n<meta charset=utf-8>\n</head>\n" +
+		"<body class=x>\n<p>Hello <A HREF='/a'>link</A> &amp; &nbsp; world\n<p>Second<br>\n" +
+		"<ul><li>one<li>two</ul>\n</body>\n</html>\n",
+	"<!doctype html>\r\n<html>\r\n<head>\r\n<title>A &amp; B</title>\r\n</head>\r\n<body>\r\n" +
+		"<div id=\"a\" title='x &quot; y'>\r\n  <img src=a.png alt=\"&lt;img&gt;\">\r\n" +
+		"  <a href=\"?a=1&b=2\">q</a>\r\n</div>\r\n</body>\r\n</html>\r\n",
+	"  <!DOCTYPE html><!--pre--><html><head></head><body></body></html><!--post-->  \n",
+	"<p>a</span>b</p><div>x</p>y</div>",
+	"<table><b>bold</b><tr><td>1<td>2</table>text",
+	"<table><tr><td>a</td></tr><div>x</div></table>",
+	"<table>\n<tr><td>a</td></tr>\n<div>x</div>\n</table>\n",
+	"<table>\n<caption>c</caption>\n<colgroup><col><col></colgroup>\n" +
+		"<thead><tr><th>h</thead>\n<tr><td>1</td></tr>\n</table>",
+	"<pre>\n\nfoo</pre><textarea>\nbar</textarea>",
+	"<svg viewBox='0 0 1 1'><circle r=1/><style>a<b</style><![CDATA[x<y]]></svg><math><mi>x</mi></math>",
+	"<script>if (a<b) {}</script><style>p>a{}</style><!-- c -->",
+	"<template><p>in template</p></template><noscript><p>ns</p></noscript><iframe src=x></iframe>",
+	"<ul>\n  <li>a\n  <li>b\n</ul>\n<p>para\n<h1>heading</h1>\n<p>x<div>y</div>",
+	"<html><body><body class=y><p>dup</p></body></html>",
+	"<body onload=x><form><input name=a value='1'><select><optgroup label=g><option selected>z</select>" +
+		"<textarea>\n\nt</textarea></form>",
+	"text only",
+}
+
+func parsePreserving(t *testing.T, htmlSource string) *dom.Document {
+	doc, err := dom.ParseDocumentWithOptions(strings.NewReader(htmlSource), dom.ParseOptions{PreserveSource: true})
+	if err != nil {
+		t.Fatalf("RenderPreserving(), failed to parse: %v", err)
+	}
+	return doc
+}
+
+func renderPreserving(t *testing.T, doc *dom.Document) string {
+	var sb strings.Builder
+	if err := doc.RenderPreserving(&sb); err != nil {
+		t.Fatalf("RenderPreserving() error = %v", err)
+	}
+	return sb.String()
+}
+
+func TestRenderPreservingUntouched(t *testing.T) {
+	for _, htmlSource := range preservingSources {
+		doc := parsePreserving(t, htmlSource)
+		if got := renderPreserving(t, doc); got != htmlSource {
+			t.Errorf("RenderPreserving() = %q, want %q", got, htmlSource)
+		}
+	}
+}
+
+func TestRenderPreserving(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		modify     func(doc *dom.Document)
+		want       string
+	}{{
+		name:       "rewrite URL",
+		htmlSource: "<p>Hello <A HREF='/a' class=x>link</A> &amp; &nbsp;<a href=/b>b</a>\n",
+		modify: func(doc *dom.Document) {
+			dom.SetAttribute(dom.QuerySelector(doc.Node, "a"), "href", "https://example.com/a")
+		},
+		want: "<p>Hello <a href=\"https://example.com/a\" class=\"x\">link</A> &amp; &nbsp;<a href=/b>b</a>\n",
+	}, {
+		name:       "change text",
+		htmlSource: "<ul>\n  <li class=first>one &amp; two\n  <li>three\n</ul>",
+		modify: func(doc *dom.Document) {
+			dom.SetTextContent(dom.QuerySelector(doc.Node, "li"), "1 < 2\n  ")
+		},
+		want: "<ul>\n  <li class=first>1 &lt; 2\n  <li>three\n</ul>",
+	}, {
+		name:       "append new element",
+		htmlSource: "<div id=main>\n  <p>First\n</div>",
+		modify: func(doc *dom.Document) {
+			p := dom.CreateElement("p")
+			dom.AppendChild(p, dom.CreateTextNode("Second"))
+			dom.AppendChild(dom.GetElementByID(doc.Node, "main"), p)
+		},
+		want: "<div id=main>\n  <p>First\n</p><p>Second</p></div>",
+	}, {
+		name:       "remove element",
+		htmlSource: "<p>para\n<ul><li>a</ul>\n<p>next",
+		modify: func(doc *dom.Document) {
+			dom.Remove(dom.QuerySelector(doc.Node, "ul"))
+		},
+		want: "<p>para\n</p>\n<p>next",
+	}, {
+		name:       "keep ignored whitespace",
+		htmlSource: "<!DOCTYPE html>\n<html>\n<head>\n<title>Old</title>\n</head>\n<body>\n<p>text</p>\n</body>\n</html>\n",
+		modify: func(doc *dom.Document) {
+			doc.SetTitle("New")
+		},
+		want: "<!DOCTYPE html>\n<html>\n<head>\n<title>New</title>\n</head>\n<body>\n<p>text</p>\n</body>\n</html>\n",
+	}, {
+		name:       "rewrite meta charset",
+		htmlSource: "<html><head><META CHARSET=\"shift_jis\"></head><body><p>text</p></body></html>",
+		modify: func(doc *dom.Document) {
+			doc.SetMetaCharset("utf-8")
+		},
+		want: "<html><head><meta charset=\"utf-8\"></head><body><p>text</p></body></html>",
+	}, {
+		name:       "modify foster-parented element",
+		htmlSource: "<table><tr><td>a</td></tr><div>x</div></table>",
+		modify: func(doc *dom.Document) {
+			dom.SetAttribute(dom.QuerySelector(doc.Node, "div"), "class", "y")
+		},
+		want: "<div class=\"y\">x</div><table><tbody><tr><td>a</td></tr></tbody></table>",
+	}, {
+		name:       "modify table with foster-parented element",
+		htmlSource: "<p>keep</p>\n<table>\n<tr><td>a</td></tr>\n<div>x</div>\n</table>\n<p>after</p>",
+		modify: func(doc *dom.Document) {
+			dom.SetTextContent(dom.QuerySelector(doc.Node, "td"), "b")
+		},
+		want: "<p>keep</p>\n<div>x</div><table>\n<tbody><tr><td>b</td></tr>\n\n</tbody></table>\n<p>after</p>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parsePreserving(t, tt.htmlSource)
+			tt.modify(doc)
+			if got := renderPreserving(t, doc); got != tt.want {
+				t.Errorf("RenderPreserving() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderPreservingReparse modifies every element in the sources, and
+// checks that the rendered HTML is parsed back into the modified tree.
+func TestRenderPreservingReparse(t *testing.T) {
+	render := func(node *html.Node) string {
+		var sb strings.Builder
+		if err := html.Render(&sb, node); err != nil {
+			t.Fatalf("RenderPreserving(), failed to render: %v", err)
+		}
+		return sb.String()
+	}
+
+	modifications := map[string]func(*html.Node){
+		"set attribute": func(node *html.Node) {
+			dom.SetAttribute(node, "data-x", "1")
+		},
+		"remove": func(node *html.Node) {
+			switch node.Data {
+			case "html", "head", "body":
+			default:
+				dom.Remove(node)
+			}
+		},
+		"append text": func(node *html.Node) {
+			// Text can't be put directly inside these elements
+			switch node.Data {
+			case "html", "head", "table", "colgroup", "thead", "tbody", "tr", "select":
+			default:
+				if !dom.IsVoidElement(node) {
+					dom.AppendChild(node, dom.CreateTextNode("new"))
+				}
+			}
+		},
+	}
+
+	for _, htmlSource := range preservingSources {
+		nElements := len(dom.GetElementsByTagName(parsePreserving(t, htmlSource).Node, "*"))
+		for name, modify := range modifications {
+			for i := 0; i < nElements; i++ {
+				doc := parsePreserving(t, htmlSource)
+				element := dom.GetElementsByTagName(doc.Node, "*")[i]
+				modify(element)
+
+				rendered := renderPreserving(t, doc)
+				reparsed, err := html.Parse(strings.NewReader(rendered))
+				if err != nil {
+					t.Fatalf("RenderPreserving(), failed to reparse: %v", err)
+				}
+
+				if got, want := render(reparsed), render(doc.Node); got != want {
+					t.Errorf("RenderPreserving() %s <%s> in %q:\nrendered %q\ngot  %q\nwant %q",
+						name, element.Data, htmlSource, rendered, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRenderPreservingEncoded(t *testing.T) {
+	htmlSource := "<html><head><meta charset=\"shift_jis\"><title>日本語のページ</title></head>\n" +
+		"<body><p>これは日本語で書かれたページです。</p><p id=b>文字コード</p></body></html>"
+
+	encode := func(s string) string {
+		encoded, _, err := transform.String(japanese.ShiftJIS.NewEncoder(), s)
+		if err != nil {
+			t.Fatalf("RenderPreserving(), failed to encode: %v", err)
+		}
+		return encoded
+	}
+
+	parse := func(opts dom.ParseOptions) *dom.Document {
+		opts.PreserveSource = true
+		doc, err := dom.ParseDocumentWithOptions(strings.NewReader(encode(htmlSource)), opts)
+		if err != nil {
+			t.Fatalf("RenderPreserving(), failed to parse: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("untouched", func(t *testing.T) {
+		doc := parse(dom.ParseOptions{})
+		if got, want := renderPreserving(t, doc), encode(htmlSource); got != want {
+			t.Errorf("RenderPreserving() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		// © can't be encoded in Shift_JIS, so it's written as character reference
+		doc := parse(dom.ParseOptions{})
+		dom.SetTextContent(dom.GetElementByID(doc.Node, "b"), "新しい段落 ©")
+
+		want := encode(strings.Replace(htmlSource, "<p id=b>文字コード</p>", "<p id=b>新しい段落 &#169;</p>", 1))
+		if got := renderPreserving(t, doc); got != want {
+			t.Errorf("RenderPreserving() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("rewrite meta charset", func(t *testing.T) {
+		doc := parse(dom.ParseOptions{RewriteMetaCharset: true})
+		want := strings.Replace(htmlSource, `<meta charset="shift_jis">`, `<meta charset="utf-8">`, 1)
+		if got := renderPreserving(t, doc); got != want {
+			t.Errorf("RenderPreserving() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestRenderPreservingWithoutSource(t *testing.T) {
+	doc, err := dom.FastParseDocument(strings.NewReader("<p>Hello<br>world"))
+	if err != nil {
+		t.Fatalf("RenderPreserving(), failed to parse: %v", err)
+	}
+
+	want := "<html><head></head><body><p>Hello<br/>world</p></body></html>"
+	if got := renderPreserving(t, doc); got != want {
+		t.Errorf("RenderPreserving() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,229 @@
+package dom
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PrettyOptions is the options for RenderPretty.
+type PrettyOptions struct {
+	// Indent is the string that used for each level of indentation.
+	// If it's empty, two spaces will be used.
+	Indent string
+
+	// LineWidth is the maximum width of line that contains inline content.
+	// Since the inline content is only broken at its existing whitespace,
+	// a line might still be longer than this. Zero disables wrapping.
+	LineWidth int
+
+	// Cascade is used to compute the display and white-space of elements.
+	// If it's nil, only the inline style is used.
+	Cascade *Cascade
+}
+
+// RenderPretty renders the node and its descendants as indented HTML. To make
+// sure the result is rendered the same as the original, whitespace is only
+// added or removed where it's insignificant, i.e. around block elements. The
+// inline content (text and inline elements) is kept inline, with its whitespace
+// collapsed and wrapped at the line width. Content of <pre>, <textarea>,
+// <script>, <style> and other elements whose whitespace is preserved, either
+// by default or by their computed style, is rendered as it is.
+func RenderPretty(w io.Writer, node *html.Node, opts PrettyOptions) error {
+	if node == nil {
+		return nil
+	}
+
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+
+	p := prettyPrinter{
+		rw:      &renderWriter{w: w},
+		opts:    opts,
+		cascade: cascadeFor(opts.Cascade),
+	}
+
+	p.writeNodes([]*html.Node{node}, 0, false)
+	return p.rw.err
+}
+
+type prettyPrinter struct {
+	rw      *renderWriter
+	opts    PrettyOptions
+	cascade *Cascade
+}
+
+// writeNodes writes the nodes in separate lines. Consecutive inline
+// nodes are grouped together and written as one paragraph. If
+// structural is true, all elements are treated as block.
+func (p *prettyPrinter) writeNodes(nodes []*html.Node, depth int, structural bool) {
+	var inlineNodes []*html.Node
+	flushInline := func() {
+		if len(inlineNodes) > 0 {
+			p.writeWords(p.inlineWords(inlineNodes), depth)
+			inlineNodes = nil
+		}
+	}
+
+	for _, node := range nodes {
+		if p.isBlock(node, structural) {
+			flushInline()
+			p.writeBlock(node, depth)
+		} else {
+			inlineNodes = append(inlineNodes, node)
+		}
+	}
+
+	flushInline()
+}
+
+func (p *prettyPrinter) writeBlock(node *html.Node, depth int) {
+	switch {
+	case node.Type == html.DocumentNode:
+		p.writeNodes(ChildNodes(node), depth, true)
+
+	case node.Type != html.ElementNode, IsVoidElement(node), isWhitespacePreserved(node, p.cascade):
+		p.writeLine(p.verbatim(node), depth)
+
+	default:
+		// Element with only inline content is written
+		// in one line, as long as it fits the line width
+		structural := isStructuralElement(node)
+		children := ChildNodes(node)
+
+		hasBlockChild := false
+		for _, child := range children {
+			if p.isBlock(child, structural) {
+				hasBlockChild = true
+				break
+			}
+		}
+
+		if !hasBlockChild {
+			line := startTag(node) + strings.Join(p.inlineWords(children), " ") + endTag(node)
+			lineWidth := textWidth(strings.Repeat(p.opts.Indent, depth) + line)
+			if !strings.Contains(line, "\n") && (p.opts.LineWidth <= 0 || lineWidth <= p.opts.LineWidth) {
+				p.writeLine(line, depth)
+				return
+			}
+		}
+
+		p.writeLine(startTag(node), depth)
+		p.writeNodes(children, depth+1, structural)
+		p.writeLine(endTag(node), depth)
+	}
+}
+
+// writeWords writes the words of inline content, separated by space and
+// wrapped at the line width.
+func (p *prettyPrinter) writeWords(words []string, depth int) {
+	if len(words) == 0 {
+		return
+	}
+
+	if p.opts.LineWidth <= 0 {
+		p.writeLine(strings.Join(words, " "), depth)
+		return
+	}
+
+	maxWidth := p.opts.LineWidth - textWidth(strings.Repeat(p.opts.Indent, depth))
+	line := words[0]
+	for _, word := range words[1:] {
+		if textWidth(line)+1+textWidth(word) > maxWidth {
+			p.writeLine(line, depth)
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+
+	p.writeLine(line, depth)
+}
+
+func (p *prettyPrinter) writeLine(line string, depth int) {
+	p.rw.writeString(strings.Repeat(p.opts.Indent, depth) + line + "\n")
+}
+
+// inlineWords renders the inline nodes and splits them at the collapsible
+// whitespace, so they can be joined again using single space or newline.
+func (p *prettyPrinter) inlineWords(nodes []*html.Node) []string {
+	var words []string
+	var current strings.Builder
+
+	space := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode && !isRawTextElement(node.Parent):
+			text := node.Data
+			fields := strings.FieldsFunc(text, isASCIIWhitespace)
+			if len(fields) == 0 {
+				if text != "" {
+					space()
+				}
+				return
+			}
+
+			if isASCIIWhitespace(rune(text[0])) {
+				space()
+			}
+
+			for i, field := range fields {
+				if i > 0 {
+					space()
+				}
+				current.WriteString(htmlEscaper.Replace(field))
+			}
+
+			if isASCIIWhitespace(rune(text[len(text)-1])) {
+				space()
+			}
+
+		case node.Type == html.ElementNode && !IsVoidElement(node) && !isWhitespacePreserved(node, p.cascade):
+			current.WriteString(startTag(node))
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				collect(child)
+			}
+			current.WriteString(endTag(node))
+
+		default:
+			current.WriteString(p.verbatim(node))
+		}
+	}
+
+	for _, node := range nodes {
+		collect(node)
+	}
+
+	space()
+	return words
+}
+
+// isBlock check whether the node is written in its own line. Besides block
+// boundary, this includes all elements inside the structural elements.
+func (p *prettyPrinter) isBlock(node *html.Node, structural bool) bool {
+	switch node.Type {
+	case html.DocumentNode, html.DoctypeNode:
+		return true
+	case html.ElementNode:
+		return structural || isBlockBoundary(node, p.cascade)
+	default:
+		return false
+	}
+}
+
+func (p *prettyPrinter) verbatim(node *html.Node) string {
+	var sb strings.Builder
+	rw := renderWriter{w: &sb}
+	rw.writeVerbatim(node)
+	p.rw.setError(rw.err)
+	return sb.String()
+}
@@ -0,0 +1,126 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestRenderPretty(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		opts       dom.PrettyOptions
+		want       string
+	}{{
+		name:       "document",
+		htmlSource: `<!DOCTYPE html><html><head><title>Test</title><meta charset="utf-8"></head><body><div><p>Hello <b>world</b>!</p><p>Second</p></div></body></html>`,
+		want: "<!DOCTYPE html>\n" +
+			"<html>\n" +
+			"  <head>\n" +
+			"    <title>Test</title>\n" +
+			"    <meta charset=\"utf-8\">\n" +
+			"  </head>\n" +
+			"  <body>\n" +
+			"    <div>\n" +
+			"      <p>Hello <b>world</b>!</p>\n" +
+			"      <p>Second</p>\n" +
+			"    </div>\n" +
+			"  </body>\n" +
+			"</html>\n",
+	}, {
+		name:       "custom indent",
+		htmlSource: `<ul><li>One</li><li>Two</li></ul>`,
+		opts:       dom.PrettyOptions{Indent: "\t"},
+		want:       "<html>\n\t<head></head>\n\t<body>\n\t\t<ul>\n\t\t\t<li>One</li>\n\t\t\t<li>Two</li>\n\t\t</ul>\n\t</body>\n</html>\n",
+	}, {
+		name:       "inline whitespace is kept",
+		htmlSource: `<body><div>a<b>b</b>c <i> d </i>   e<br>f</div></body>`,
+		want:       "<html>\n  <head></head>\n  <body>\n    <div>a<b>b</b>c <i> d </i> e<br>f</div>\n  </body>\n</html>\n",
+	}, {
+		name:       "mixed block and inline",
+		htmlSource: `<body><div> Text before <p>Para</p> text after </div></body>`,
+		want: "<html>\n  <head></head>\n  <body>\n    <div>\n" +
+			"      Text before\n      <p>Para</p>\n      text after\n" +
+			"    </div>\n  </body>\n</html>\n",
+	}, {
+		name:       "wrap long line",
+		htmlSource: `<body><p>The quick <a href="#">brown fox</a> jumps over the lazy dog</p></body>`,
+		opts:       dom.PrettyOptions{LineWidth: 30},
+		want: "<html>\n  <head></head>\n  <body>\n    <p>\n" +
+			"      The quick\n" +
+			"      <a href=\"#\">brown\n" +
+			"      fox</a> jumps over the\n" +
+			"      lazy dog\n" +
+			"    </p>\n  </body>\n</html>\n",
+	}, {
+		name: "preserved content",
+		htmlSource: "<body><pre>\n\n  line 1\n    line 2</pre><textarea>  a\n b</textarea>" +
+			"<p><script>if (a < b) {\n  run()\n}</script></p><div style=\"white-space: pre\"> x  <b>y</b> </div></body>",
+		want: "<html>\n  <head></head>\n  <body>\n" +
+			"    <pre>\n\n  line 1\n    line 2</pre>\n" +
+			"    <textarea>  a\n b</textarea>\n" +
+			"    <p>\n      <script>if (a < b) {\n  run()\n}</script>\n    </p>\n" +
+			"    <div style=\"white-space: pre\"> x  <b>y</b> </div>\n" +
+			"  </body>\n</html>\n",
+	}, {
+		name:       "table",
+		htmlSource: `<table><tr><td>A</td><td>B <i>b</i></td></tr></table>`,
+		want: "<html>\n  <head></head>\n  <body>\n    <table>\n      <tbody>\n        <tr>\n" +
+			"          <td>A</td>\n          <td>B <i>b</i></td>\n" +
+			"        </tr>\n      </tbody>\n    </table>\n  </body>\n</html>\n",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := dom.Parse(strings.NewReader(tt.htmlSource))
+			if err != nil {
+				t.Fatalf("RenderPretty(), failed to parse: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := dom.RenderPretty(&sb, doc, tt.opts); err != nil {
+				t.Fatalf("RenderPretty() error = %v", err)
+			}
+
+			got := sb.String()
+			if got != tt.want {
+				t.Errorf("RenderPretty() = %q, want %q", got, tt.want)
+			}
+
+			// Pretty printing must not change the rendered text
+			reparsed, err := dom.Parse(strings.NewReader(got))
+			if err != nil {
+				t.Fatalf("RenderPretty(), failed to parse result: %v", err)
+			}
+
+			wantText := dom.InnerText(dom.QuerySelector(doc, "body"))
+			gotText := dom.InnerText(dom.QuerySelector(reparsed, "body"))
+			if gotText != wantText {
+				t.Errorf("RenderPretty() text = %q, want %q", gotText, wantText)
+			}
+		})
+	}
+
+	t.Run("custom cascade", func(t *testing.T) {
+		doc, err := dom.Parse(strings.NewReader("<link rel=\"stylesheet\" href=\"main.css\"><div class=\"code\">a\n  b</div>"))
+		if err != nil {
+			t.Fatalf("RenderPretty(), failed to parse: %v", err)
+		}
+
+		cascade := dom.NewCascade(doc, dom.CascadeOptions{
+			ResolveStylesheet: func(string) (string, bool) { return ".code { white-space: pre }", true },
+		})
+
+		var sb strings.Builder
+		div := dom.QuerySelector(doc, "div")
+		if err := dom.RenderPretty(&sb, div, dom.PrettyOptions{Cascade: cascade}); err != nil {
+			t.Fatalf("RenderPretty() error = %v", err)
+		}
+
+		if got, want := sb.String(), "<div class=\"code\">a\n  b</div>\n"; got != want {
+			t.Errorf("RenderPretty() = %q, want %q", got, want)
+		}
+	})
+}
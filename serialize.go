@@ -0,0 +1,321 @@
+package dom
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlEscaper escapes text and attribute value in the same way as html.Render.
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"'", "&#39;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"\r", "&#13;",
+)
+
+// renderWriter wraps the writer and keeps its first error, so the
+// serializers don't need to check the error on every write.
+type renderWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (rw *renderWriter) writeString(s string) {
+	if rw.err != nil || s == "" {
+		return
+	}
+	_, rw.err = io.WriteString(rw.w, s)
+}
+
+// writeVerbatim writes the node and its descendants without changing
+// their whitespace. It's similar with html.Render, except the void
+// elements are not self-closed.
+func (rw *renderWriter) writeVerbatim(node *html.Node) {
+	switch node.Type {
+	case html.ErrorNode:
+		rw.setError(errors.New("dom: cannot render an ErrorNode node"))
+	case html.TextNode:
+		if isRawTextElement(node.Parent) {
+			rw.writeString(node.Data)
+		} else {
+			rw.writeString(htmlEscaper.Replace(node.Data))
+		}
+	case html.DocumentNode:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			rw.writeVerbatim(child)
+		}
+	case html.ElementNode:
+		rw.writeString(startTag(node))
+		if IsVoidElement(node) {
+			if node.FirstChild != nil {
+				rw.setError(errors.New("dom: void element <" + node.Data + "> has child nodes"))
+			}
+			return
+		}
+
+		if needsLeadingNewline(node) {
+			rw.writeString("\n")
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			rw.writeVerbatim(child)
+		}
+		rw.writeString(endTag(node))
+	default:
+		// Comment and doctype are rendered by html.Render,
+		// so they are escaped in the same way
+		rw.setError(html.Render(writerFunc(rw.writeBytes), node))
+	}
+}
+
+func (rw *renderWriter) writeBytes(p []byte) (int, error) {
+	rw.writeString(string(p))
+	return len(p), rw.err
+}
+
+func (rw *renderWriter) setError(err error) {
+	if rw.err == nil {
+		rw.err = err
+	}
+}
+
+// writerFunc is an adapter to allow the use of function as io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// startTag returns the start tag of the element, with its
+// attributes rendered in their original order.
+func startTag(node *html.Node) string {
+	var sb strings.Builder
+	sb.WriteString("<" + node.Data)
+	for _, attr := range node.Attr {
+		sb.WriteString(" " + attributeQualifiedName(attr) + `="` + htmlEscaper.Replace(attr.Val) + `"`)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+func endTag(node *html.Node) string {
+	return "</" + node.Data + ">"
+}
+
+// isRawTextElement check whether the content of the element is
+// rendered as raw text, i.e. it's not escaped.
+func isRawTextElement(node *html.Node) bool {
+	if node == nil || node.Type != html.ElementNode || node.Namespace != "" {
+		return false
+	}
+
+	switch node.Data {
+	case "iframe", "noembed", "noframes", "noscript", "plaintext", "script", "style", "xmp":
+		return true
+	default:
+		return false
+	}
+}
+
+// needsLeadingNewline check whether an extra newline must be written at the
+// start of the element's content, since the parser ignores the first newline
+// in <pre>, <listing> and <textarea>.
+func needsLeadingNewline(node *html.Node) bool {
+	if node.Namespace != "" {
+		return false
+	}
+
+	switch node.Data {
+	case "pre", "listing", "textarea":
+		child := node.FirstChild
+		return child != nil && child.Type == html.TextNode && strings.HasPrefix(child.Data, "\n")
+	default:
+		return false
+	}
+}
+
+// isBlockBoundary check whether the element is a boundary where the
+// whitespace around it is not rendered, i.e. block-level elements and
+// table parts, which whitespace between them is not rendered as well.
+func isBlockBoundary(node *html.Node, cascade *Cascade) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+
+	display := cascade.ComputedStyle(node).Display
+	return isBlockLevelDisplay(display) || strings.HasPrefix(display, "table")
+}
+
+// isWhitespacePreserved check whether the whitespace in the element's
+// content is significant, either because it's a raw text element, or
+// because its white-space style preserves it.
+func isWhitespacePreserved(node *html.Node, cascade *Cascade) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+
+	if isRawTextElement(node) {
+		return true
+	}
+
+	if node.Namespace == "" {
+		switch node.Data {
+		case "pre", "textarea", "listing":
+			return true
+		}
+	}
+
+	switch cascade.ComputedStyle(node).WhiteSpace {
+	case "pre", "pre-wrap", "pre-line", "break-spaces":
+		return true
+	default:
+		return false
+	}
+}
+
+// isStructuralElement check whether the element only contains other
+// elements that structure the document, i.e. <html> and <head>.
+func isStructuralElement(node *html.Node) bool {
+	return node != nil && (isHTMLElement(node, "html") || isHTMLElement(node, "head"))
+}
+
+// collapseWhitespace collapses the whitespace in the text nodes that are not
+// inside preserved context, and returns the collapsed text of each node. The
+// whitespace at the start and the end of line, i.e. around block boundary, is
+// removed, and the whitespace that directly follows another whitespace is
+// removed as well, even across inline elements.
+func collapseWhitespace(root *html.Node, cascade *Cascade) map[*html.Node]string {
+	texts := make(map[*html.Node]string)
+	for parent := root.Parent; parent != nil; parent = parent.Parent {
+		if isWhitespacePreserved(parent, cascade) {
+			return texts
+		}
+	}
+
+	// Flatten the tree into sequence of text, block boundary
+	// and atomic content, which ends the whitespace collapsing
+	const (
+		boundary = iota
+		content
+		text
+	)
+
+	type item struct {
+		kind int
+		node *html.Node
+	}
+
+	var items []item
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			items = append(items, item{kind: text, node: node})
+			texts[node] = collapseASCIIWhitespace(node.Data)
+			return
+		case html.DocumentNode:
+			items = append(items, item{kind: boundary})
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+			items = append(items, item{kind: boundary})
+			return
+		case html.ElementNode:
+		default:
+			return
+		}
+
+		isBoundary := isStructuralElement(node) || isStructuralElement(node.Parent) ||
+			isBlockBoundary(node, cascade) || isHTMLElement(node, "br")
+
+		if isBoundary {
+			items = append(items, item{kind: boundary})
+		}
+
+		switch {
+		case isRawTextElement(node) && cascade.ComputedStyle(node).Display == "none":
+			// Hidden raw text elements like <script> doesn't affect whitespace
+		case node.Namespace != "", IsVoidElement(node), isWhitespacePreserved(node, cascade),
+			!isBoundary && strings.HasPrefix(cascade.ComputedStyle(node).Display, "inline-"):
+			items = append(items, item{kind: content})
+		default:
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+		}
+
+		if isBoundary {
+			items = append(items, item{kind: boundary})
+		}
+	}
+
+	walk(root)
+
+	// Remove the leading space at the start of line or after another space
+	afterSpace := false
+	for _, it := range items {
+		switch it.kind {
+		case boundary:
+			afterSpace = true
+		case content:
+			afterSpace = false
+		default:
+			str := texts[it.node]
+			if afterSpace {
+				str = strings.TrimPrefix(str, " ")
+			}
+
+			if str != "" {
+				afterSpace = strings.HasSuffix(str, " ")
+			}
+			texts[it.node] = str
+		}
+	}
+
+	// Remove the trailing space at the end of line
+	beforeBoundary := false
+	for i := len(items) - 1; i >= 0; i-- {
+		switch it := items[i]; it.kind {
+		case boundary:
+			beforeBoundary = true
+		case content:
+			beforeBoundary = false
+		default:
+			str := texts[it.node]
+			if beforeBoundary {
+				str = strings.TrimSuffix(str, " ")
+			}
+
+			if str != "" {
+				beforeBoundary = false
+			}
+			texts[it.node] = str
+		}
+	}
+
+	return texts
+}
+
+// collapseASCIIWhitespace replaces each sequence of ASCII whitespace with a single space.
+func collapseASCIIWhitespace(text string) string {
+	var sb strings.Builder
+	inSpace := false
+	for _, char := range text {
+		if isASCIIWhitespace(char) {
+			if !inSpace {
+				sb.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+
+		sb.WriteRune(char)
+		inSpace = false
+	}
+	return sb.String()
+}
@@ -0,0 +1,673 @@
+package dom
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+)
+
+// sourceLookahead is the maximum number of tags that checked while aligning
+// the tokens with the parsed nodes, to decide whether a node is implied by
+// the parser or a token is ignored by the parser.
+const sourceLookahead = 16
+
+// sourceToken is a token in the document source, along with
+// its position and the node that created from it.
+type sourceToken struct {
+	html.Token
+	start, end int
+	owner      *html.Node
+}
+
+// sourceNode is the source of a node, recorded when the document is parsed.
+type sourceNode struct {
+	// startTag and endTag is the index of the token where the element is
+	// opened and closed, or -1 if it's implied by the parser. For the other
+	// node types, they are the first and last token where the node is
+	// created, since the parser might merge several text into one node.
+	startTag, endTag int
+
+	// first and last is the index of the first and last token that owned
+	// by the node and its descendants, or -1 if there are none.
+	first, last int
+
+	// selfContained is true if all tokens between first and last are owned
+	// by the node's subtree or ignored by the parser, so the node can be
+	// reproduced by copying its source.
+	selfContained bool
+
+	// data, attr, children, parent and next is the snapshot of the node when
+	// it's parsed, which used to check whether the node has been modified.
+	data     string
+	attr     []html.Attribute
+	children []*html.Node
+	parent   *html.Node
+	next     *html.Node
+
+	// run is the siblings whose source is interleaved since the parser
+	// reordered them, e.g. content that foster-parented before <table>,
+	// or nil if the node is in the same order as its source.
+	run *sourceRun
+}
+
+// sourceRun is the siblings that reordered by the parser, which must be
+// serialized together since their source can't be copied in order.
+type sourceRun struct {
+	// first and last is the index of the first and last token
+	// that owned by the siblings and their descendants.
+	first, last int
+}
+
+// sourceMap maps the nodes of parsed document into their source.
+type sourceMap struct {
+	source []byte
+	tokens []sourceToken
+	nodes  map[*html.Node]*sourceNode
+
+	// input is the original input, before it's converted into UTF-8.
+	input []byte
+
+	// offsets maps the offset in the source into the original input, which
+	// is different when the input is not UTF-8. Nil means they are the same.
+	offsets []sourceOffset
+
+	// encoding is the encoding of the original input that used to render
+	// the document, or nil if it's rendered as UTF-8 using the source.
+	encoding encoding.Encoding
+
+	// lineStarts is the offset of the start of each line in the source.
+	lineStarts []int
+}
+
+// sourceOffset is the offset of a character in the decoded
+// source and its offset in the original input.
+type sourceOffset struct {
+	decoded, original int
+}
+
+// newSourceMap creates the source map of the root that parsed from the source.
+// Since the parser doesn't report the position of nodes, the source is tokenized
+// separately and its tokens are aligned with the nodes in document order. Nodes
+// that can't be aligned, e.g. those implied or moved by the parser, don't have
+// any source, and will be serialized when rendered. If the input is not UTF-8,
+// the source is the decoded input and offsets maps it back into the input.
+func newSourceMap(input, source []byte, offsets []sourceOffset, enc encoding.Encoding, root *html.Node) *sourceMap {
+	m := &sourceMap{
+		source:     source,
+		tokens:     tokenizeSource(source),
+		nodes:      make(map[*html.Node]*sourceNode),
+		input:      input,
+		offsets:    offsets,
+		lineStarts: []int{0},
+	}
+
+	// Input that already in UTF-8 is rendered from the source
+	if offsets != nil {
+		m.encoding = enc
+	}
+
+	for i, char := range source {
+		if char == '\n' {
+			m.lineStarts = append(m.lineStarts, i+1)
+		}
+	}
+
+	a := sourceAligner{m: m, elementIndex: make(map[*html.Node]int)}
+	for _, node := range GetElementsByTagName(root, "*") {
+		a.elementIndex[node] = len(a.elements)
+		a.elements = append(a.elements, node)
+	}
+
+	a.align(root)
+	a.alignUnmatchedText()
+	m.computeRanges(root)
+	m.computeSelfContained(root)
+	m.computeReordered(root)
+	return m
+}
+
+// tokenizeSource splits the source into tokens. Like the parser, the content
+// of foreign elements is not tokenized as raw text and may contain CDATA.
+func tokenizeSource(source []byte) []sourceToken {
+	var tokens []sourceToken
+	var offset, foreignDepth int
+
+	z := html.NewTokenizer(bytes.NewReader(source))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			return tokens
+		}
+
+		size := len(z.Raw())
+		token := z.Token()
+		tokens = append(tokens, sourceToken{Token: token, start: offset, end: offset + size})
+		offset += size
+
+		isForeignRoot := token.Data == "svg" || token.Data == "math"
+		switch {
+		case tokenType == html.StartTagToken && isForeignRoot:
+			foreignDepth++
+		case tokenType == html.EndTagToken && isForeignRoot && foreignDepth > 0:
+			foreignDepth--
+		}
+
+		z.AllowCDATA(foreignDepth > 0)
+		if tokenType == html.StartTagToken && foreignDepth > 0 {
+			z.NextIsNotRawText()
+		}
+	}
+}
+
+type sourceAligner struct {
+	m      *sourceMap
+	cursor int
+
+	// elements is all elements in document order,
+	// used to look ahead the nodes while aligning.
+	elements     []*html.Node
+	elementIndex map[*html.Node]int
+
+	// open is the names of the elements that currently open.
+	open []string
+
+	// unmatchedText is the text nodes whose tokens are not found.
+	unmatchedText []*html.Node
+}
+
+func (a *sourceAligner) align(node *html.Node) {
+	sn := &sourceNode{
+		startTag: -1,
+		endTag:   -1,
+		data:     node.Data,
+		attr:     append([]html.Attribute(nil), node.Attr...),
+		children: ChildNodes(node),
+		parent:   node.Parent,
+		next:     node.NextSibling,
+	}
+	a.m.nodes[node] = sn
+
+	switch node.Type {
+	case html.DocumentNode:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			a.align(child)
+		}
+
+	case html.ElementNode:
+		sn.startTag = a.matchStartTag(node)
+		a.open = append(a.open, strings.ToLower(node.Data))
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			a.align(child)
+		}
+		a.open = a.open[:len(a.open)-1]
+
+		// Foreign element can be self-closed, which doesn't have end tag
+		if sn.startTag < 0 || node.Namespace == "" || a.m.tokens[sn.startTag].Type == html.StartTagToken {
+			sn.endTag = a.matchEndTag(node)
+		}
+
+	case html.TextNode:
+		sn.startTag, sn.endTag = a.matchText(node)
+		if sn.startTag < 0 {
+			a.unmatchedText = append(a.unmatchedText, node)
+		}
+
+	default:
+		sn.startTag = a.matchToken(node)
+	}
+
+	for _, idx := range []int{sn.startTag, sn.endTag} {
+		if idx >= 0 {
+			a.m.tokens[idx].owner = node
+		}
+	}
+}
+
+// matchStartTag returns the index of start tag that creates the element, or -1
+// if it's implied. Start tags that ignored by the parser are skipped.
+func (a *sourceAligner) matchStartTag(node *html.Node) int {
+	for {
+		idx := a.nextStartTag(a.cursor)
+		if idx < 0 {
+			return -1
+		}
+
+		if tokenCreatesElement(a.m.tokens[idx], node) {
+			a.cursor = idx + 1
+			return idx
+		}
+
+		// Either the node is implied by the parser, or the token is ignored
+		// by the parser. Pick the one whose counterpart is further away.
+		nodeDistance := a.nodeDistance(node, idx)
+		tokenDistance := a.tokenDistance(node, idx)
+		if nodeDistance < 0 || (tokenDistance >= 0 && tokenDistance < nodeDistance) {
+			return -1
+		}
+		a.cursor = idx + 1
+	}
+}
+
+// matchEndTag returns the index of end tag that closes the element, or -1 if
+// it's implied. End tags that don't close any open element are skipped.
+func (a *sourceAligner) matchEndTag(node *html.Node) int {
+	if IsVoidElement(node) {
+		return -1
+	}
+
+	tagName := strings.ToLower(node.Data)
+	for idx := a.cursor; idx < len(a.m.tokens); idx++ {
+		token := a.m.tokens[idx]
+		if token.Type != html.EndTagToken {
+			return -1
+		}
+
+		if token.Data == tagName {
+			a.cursor = idx + 1
+			return idx
+		}
+
+		for _, openTag := range a.open {
+			if token.Data == openTag {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// matchText returns the index of the first and last token that creates the
+// text node, or -1 if there are none before the next start tag. The text
+// might be created from several text tokens, e.g. the whitespace after
+// </body> is appended into the last text of <body>.
+func (a *sourceAligner) matchText(node *html.Node) (int, int) {
+	for idx := a.cursor; idx < len(a.m.tokens); idx++ {
+		if last := a.matchTextFrom(node, idx); last >= 0 {
+			a.cursor = last + 1
+			return idx, last
+		}
+
+		token := a.m.tokens[idx]
+		if token.Type != html.EndTagToken && !isWhitespaceToken(token) {
+			return -1, -1
+		}
+	}
+	return -1, -1
+}
+
+// alignUnmatchedText matches the text nodes that moved by the parser, e.g.
+// the whitespace after </html> which appended into <body>, with the text
+// tokens that not owned by any node. This prevents their tokens to be
+// treated as ignored, which will duplicate the text when rendered.
+func (a *sourceAligner) alignUnmatchedText() {
+	for _, node := range a.unmatchedText {
+		start := 0
+		if parent := a.m.nodes[node.Parent]; parent != nil && parent.startTag >= 0 {
+			start = parent.startTag
+		}
+
+		for idx := start; idx < len(a.m.tokens); idx++ {
+			if a.m.tokens[idx].owner != nil {
+				continue
+			}
+
+			if last := a.matchTextFrom(node, idx); last >= 0 {
+				sn := a.m.nodes[node]
+				sn.startTag, sn.endTag = idx, last
+				break
+			}
+		}
+	}
+}
+
+// matchTextFrom returns the index of the last token that creates the
+// text node starting from idx, or -1 if the text doesn't match. End tags
+// between the text tokens are skipped, since they are ignored.
+func (a *sourceAligner) matchTextFrom(node *html.Node, idx int) int {
+	if a.m.tokens[idx].Type != html.TextToken {
+		return -1
+	}
+
+	// The parser ignores the first newline in <pre>, <listing> and <textarea>
+	remaining := node.Data
+	ignoreNewline := false
+	if parent := node.Parent; parent != nil && parent.FirstChild == node && parent.Namespace == "" {
+		switch parent.Data {
+		case "pre", "listing", "textarea":
+			ignoreNewline = true
+		}
+	}
+
+	var textTokens []int
+	for ; idx < len(a.m.tokens) && remaining != ""; idx++ {
+		token := a.m.tokens[idx]
+		if token.Type == html.EndTagToken && len(textTokens) > 0 {
+			continue
+		}
+
+		data := token.Data
+		if ignoreNewline && len(textTokens) == 0 {
+			data = strings.TrimPrefix(data, "\n")
+		}
+
+		if token.Type != html.TextToken || token.owner != nil || !strings.HasPrefix(remaining, data) {
+			return -1
+		}
+
+		remaining = remaining[len(data):]
+		textTokens = append(textTokens, idx)
+	}
+
+	if remaining != "" || len(textTokens) == 0 {
+		return -1
+	}
+
+	for _, textIdx := range textTokens {
+		a.m.tokens[textIdx].owner = node
+	}
+	return textTokens[len(textTokens)-1]
+}
+
+// matchToken returns the index of token that creates the comment or
+// doctype node, or -1 if there are none before the next start tag.
+func (a *sourceAligner) matchToken(node *html.Node) int {
+	for idx := a.cursor; idx < len(a.m.tokens); idx++ {
+		token := a.m.tokens[idx]
+		if tokenCreatesNode(token, node) {
+			a.cursor = idx + 1
+			return idx
+		}
+
+		if token.Type != html.EndTagToken && !isWhitespaceToken(token) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// nextStartTag returns the index of the first start tag from idx.
+func (a *sourceAligner) nextStartTag(idx int) int {
+	for ; idx < len(a.m.tokens); idx++ {
+		switch a.m.tokens[idx].Type {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			return idx
+		}
+	}
+	return -1
+}
+
+// nodeDistance returns the number of start tags after idx until the one that
+// creates the node, or -1 if it's not found within the lookahead.
+func (a *sourceAligner) nodeDistance(node *html.Node, idx int) int {
+	for distance := 1; distance <= sourceLookahead; distance++ {
+		if idx = a.nextStartTag(idx + 1); idx < 0 {
+			return -1
+		}
+
+		if tokenCreatesElement(a.m.tokens[idx], node) {
+			return distance
+		}
+	}
+	return -1
+}
+
+// tokenDistance returns the number of elements after the node until the one
+// that created by the token, or -1 if it's not found within the lookahead.
+func (a *sourceAligner) tokenDistance(node *html.Node, idx int) int {
+	start := a.elementIndex[node]
+	for distance := 1; distance <= sourceLookahead && start+distance < len(a.elements); distance++ {
+		if tokenCreatesElement(a.m.tokens[idx], a.elements[start+distance]) {
+			return distance
+		}
+	}
+	return -1
+}
+
+// computeRanges computes the range of tokens that owned by the node and its
+// descendants, and returns it.
+func (m *sourceMap) computeRanges(node *html.Node) (int, int) {
+	sn := m.nodes[node]
+	first, last := -1, -1
+	include := func(start, end int) {
+		if start >= 0 && (first < 0 || start < first) {
+			first = start
+		}
+		if end > last {
+			last = end
+		}
+	}
+
+	include(sn.startTag, sn.startTag)
+	include(sn.endTag, sn.endTag)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		include(m.computeRanges(child))
+	}
+
+	sn.first, sn.last = first, last
+	return first, last
+}
+
+// computeSelfContained checks whether each node can be reproduced from its source.
+func (m *sourceMap) computeSelfContained(root *html.Node) {
+	// Give each node its index in document order, so the subtree
+	// of a node is a continuous range of indices
+	var nodes []*html.Node
+	var subtreeEnds []int
+	indices := make(map[*html.Node]int)
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		idx := len(nodes)
+		indices[node] = idx
+		nodes = append(nodes, node)
+		subtreeEnds = append(subtreeEnds, 0)
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		subtreeEnds[idx] = len(nodes)
+	}
+	walk(root)
+
+	for i, node := range nodes {
+		sn := m.nodes[node]
+		if sn.first < 0 {
+			continue
+		}
+
+		subtreeEnd := subtreeEnds[i]
+		sn.selfContained = true
+		for _, token := range m.tokens[sn.first : sn.last+1] {
+			if token.owner == nil {
+				if !isIgnoredToken(token) {
+					sn.selfContained = false
+					break
+				}
+				continue
+			}
+
+			if idx := indices[token.owner]; idx < i || idx >= subtreeEnd {
+				sn.selfContained = false
+				break
+			}
+		}
+	}
+
+	// Element whose start tag is missing from its source can't be reproduced,
+	// unless the parser implies it. The same goes for its ancestors.
+	for _, node := range nodes {
+		sn := m.nodes[node]
+		if node.Type != html.ElementNode || sn.startTag >= 0 || canImplyStartTag(node, sn) {
+			continue
+		}
+
+		for ; node != nil; node = node.Parent {
+			m.nodes[node].selfContained = false
+		}
+	}
+}
+
+// computeReordered finds the nodes that come before another node in document
+// order while their source comes after it, i.e. the parser has moved them, and
+// marks the siblings that contain both of them as a reordered run. Whitespace
+// is skipped since it's moved after </body> without changing the structure.
+func (m *sourceMap) computeReordered(root *html.Node) {
+	var latest *html.Node
+	latestToken := -1
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		switch idx := m.nodes[node].startTag; {
+		case idx < 0, node.Type == html.TextNode && strings.TrimLeft(node.Data, " \t\n\f\r") == "":
+		case idx < latestToken:
+			m.markReordered(latest, node)
+		default:
+			latest, latestToken = node, idx
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// markReordered marks the children of the common ancestor of a and b, from the
+// one that contains a until the one that contains b, as a reordered run. The
+// runs that overlap with them are merged.
+func (m *sourceMap) markReordered(a, b *html.Node) {
+	pathChild := make(map[*html.Node]*html.Node)
+	for child, node := a, a.Parent; node != nil; child, node = node, node.Parent {
+		pathChild[node] = child
+	}
+
+	var start, end *html.Node
+	for child, node := b, b.Parent; node != nil; child, node = node, node.Parent {
+		if c, found := pathChild[node]; found {
+			start, end = c, child
+			break
+		}
+	}
+
+	if start == nil {
+		return
+	}
+
+	if run := m.nodes[start].run; run != nil {
+		for start.PrevSibling != nil && m.nodes[start.PrevSibling].run == run {
+			start = start.PrevSibling
+		}
+	}
+
+	if run := m.nodes[end].run; run != nil {
+		for end.NextSibling != nil && m.nodes[end.NextSibling].run == run {
+			end = end.NextSibling
+		}
+	}
+
+	run := &sourceRun{first: -1, last: -1}
+	tagNames := make(map[string]bool)
+	for node := start; ; node = node.NextSibling {
+		sn := m.nodes[node]
+		sn.run = run
+		if sn.first >= 0 && (run.first < 0 || sn.first < run.first) {
+			run.first = sn.first
+		}
+		if sn.last > run.last {
+			run.last = sn.last
+		}
+
+		if node.Type == html.ElementNode {
+			tagNames[strings.ToLower(node.Data)] = true
+		}
+		for _, element := range GetElementsByTagName(node, "*") {
+			tagNames[strings.ToLower(element.Data)] = true
+		}
+
+		if node == end {
+			break
+		}
+	}
+
+	// The end tags of the moved content that can't be aligned, e.g. </div>
+	// and </table> in <table><div>x</div></table>, belong to the run as well
+	for run.last >= 0 && run.last+1 < len(m.tokens) {
+		token := m.tokens[run.last+1]
+		if token.owner != nil || token.Type != html.EndTagToken || !tagNames[token.Data] {
+			break
+		}
+		run.last++
+	}
+
+	for node := start.Parent; node != nil; node = node.Parent {
+		if sn := m.nodes[node]; sn.last < run.last {
+			sn.last = run.last
+		}
+	}
+}
+
+// canImplyStartTag check whether the start tag of element can be implied by
+// the parser, i.e. the elements created from their content like <body> and
+// <tbody>, the reconstructed formatting elements, and the empty <p> created
+// by </p>.
+func canImplyStartTag(node *html.Node, sn *sourceNode) bool {
+	if node.Namespace != "" {
+		return false
+	}
+
+	switch node.Data {
+	case "html", "head", "body", "tbody", "tr", "colgroup":
+		return true
+	case "a", "b", "big", "code", "em", "font", "i", "nobr", "s", "small", "strike", "strong", "tt", "u":
+		return true
+	case "p":
+		return sn.endTag >= 0 && len(sn.children) == 0
+	default:
+		return false
+	}
+}
+
+// tokenCreatesElement check whether the start tag token creates the element.
+func tokenCreatesElement(token sourceToken, node *html.Node) bool {
+	if token.Type != html.StartTagToken && token.Type != html.SelfClosingTagToken {
+		return false
+	}
+
+	if token.Data != strings.ToLower(node.Data) || len(token.Attr) != len(node.Attr) {
+		return false
+	}
+
+	for i, attr := range node.Attr {
+		if token.Attr[i].Key != strings.ToLower(attributeQualifiedName(attr)) || token.Attr[i].Val != attr.Val {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenCreatesNode check whether the token creates the comment or doctype node.
+func tokenCreatesNode(token sourceToken, node *html.Node) bool {
+	switch node.Type {
+	case html.CommentNode:
+		return token.Type == html.CommentToken && token.Data == node.Data
+	case html.DoctypeNode:
+		return token.Type == html.DoctypeToken && strings.EqualFold(token.Data, node.Data)
+	default:
+		return false
+	}
+}
+
+// isIgnoredToken check whether the token that doesn't create any node can be
+// safely copied, i.e. whitespace or end tag that ignored by the parser. End
+// tag of <p> and <br> is excluded since it creates a new element.
+func isIgnoredToken(token sourceToken) bool {
+	if token.Type == html.EndTagToken {
+		return token.Data != "p" && token.Data != "br"
+	}
+	return isWhitespaceToken(token)
+}
+
+func isWhitespaceToken(token sourceToken) bool {
+	return token.Type == html.TextToken && strings.TrimLeft(token.Data, " \t\n\f\r") == ""
+}
@@ -0,0 +1,129 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// StyleDeclaration is the parsed inline style of an element, similar with
+// CSSStyleDeclaration that returned by `style` in JS. It's a live view which
+// works directly on the style attribute of its node. Shorthand properties are
+// not expanded, so they are only accessible using the shorthand name.
+type StyleDeclaration struct {
+	node *html.Node
+}
+
+// Style returns the StyleDeclaration for the inline style of the node.
+func Style(node *html.Node) *StyleDeclaration {
+	return &StyleDeclaration{node: node}
+}
+
+// Length returns the number of properties in the declaration.
+func (sd *StyleDeclaration) Length() int {
+	return len(sd.declarations())
+}
+
+// Item returns the name of property with the specified index, or
+// empty string if the index is out of range.
+func (sd *StyleDeclaration) Item(index int) string {
+	declarations := sd.declarations()
+	if index < 0 || index >= len(declarations) {
+		return ""
+	}
+	return declarations[index].name
+}
+
+// GetPropertyValue returns the value of the specified property, or
+// empty string if the property is not set.
+func (sd *StyleDeclaration) GetPropertyValue(name string) string {
+	if decl, exist := sd.find(name); exist {
+		return decl.value
+	}
+	return ""
+}
+
+// GetPropertyPriority returns "important" if the specified property
+// is set with !important flag, or empty string otherwise.
+func (sd *StyleDeclaration) GetPropertyPriority(name string) string {
+	if decl, exist := sd.find(name); exist && decl.important {
+		return "important"
+	}
+	return ""
+}
+
+// SetProperty sets the value of the specified property. The priority can
+// be either "important" or empty string. If the value is empty, the property
+// will be removed instead. Invalid name, value or priority is ignored.
+func (sd *StyleDeclaration) SetProperty(name string, value string, priority string) {
+	if value == "" {
+		sd.RemoveProperty(name)
+		return
+	}
+
+	if priority != "" && priority != "important" {
+		return
+	}
+
+	// Make sure the name and value are valid, i.e. they don't break the
+	// declarations, e.g. name "a:b" which would set "b:" into property "a"
+	if name == "" || strings.ContainsAny(name, ":;") {
+		return
+	}
+
+	parsed := parseCSSDeclarations(name + ":" + value)
+	if len(parsed) != 1 || parsed[0].important || parsed[0].name != normalizeCSSPropertyName(name) {
+		return
+	}
+
+	decl := parsed[0]
+	decl.important = priority == "important"
+	sd.update(setCSSDeclaration(sd.declarations(), decl, true))
+}
+
+// RemoveProperty removes the specified property, and returns
+// its value before it's removed.
+func (sd *StyleDeclaration) RemoveProperty(name string) string {
+	declarations := sd.declarations()
+	for i, decl := range declarations {
+		if decl.name == normalizeCSSPropertyName(name) {
+			sd.update(append(declarations[:i], declarations[i+1:]...))
+			return decl.value
+		}
+	}
+	return ""
+}
+
+// CSSText returns the serialized text of the declaration.
+func (sd *StyleDeclaration) CSSText() string {
+	return serializeCSSDeclarations(sd.declarations())
+}
+
+// SetCSSText replaces the whole declaration by parsing the specified text.
+func (sd *StyleDeclaration) SetCSSText(text string) {
+	sd.update(parseCSSDeclarations(text))
+}
+
+func (sd *StyleDeclaration) declarations() []cssDeclaration {
+	return parseCSSDeclarations(GetAttribute(sd.node, "style"))
+}
+
+func (sd *StyleDeclaration) find(name string) (cssDeclaration, bool) {
+	name = normalizeCSSPropertyName(name)
+	for _, decl := range sd.declarations() {
+		if decl.name == name {
+			return decl, true
+		}
+	}
+	return cssDeclaration{}, false
+}
+
+// update writes the declarations back into style attribute. If the attribute
+// doesn't exist and there are no declarations, the attribute won't be created.
+func (sd *StyleDeclaration) update(declarations []cssDeclaration) {
+	if !HasAttribute(sd.node, "style") && len(declarations) == 0 {
+		return
+	}
+
+	SetAttribute(sd.node, "style", serializeCSSDeclarations(declarations))
+}
@@ -0,0 +1,179 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestStyleGetProperty(t *testing.T) {
+	htmlSource := `<div style="Color: red; display : none !important;` +
+		` background: url(data:image/png;base64,AAAA) no-repeat; content: 'a;b:c';` +
+		` /* margin: 0; */ font-family: &quot;Open Sans&quot;, serif; width:; --Main-Color: #fff;` +
+		` invalid declaration; visibility: hidden ! IMPORTANT"></div>`
+
+	doc, err := parseHTMLSource(htmlSource)
+	if err != nil {
+		t.Errorf("Style(), failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		wantValue    string
+		wantPriority string
+	}{
+		{"color", "red", ""},
+		{"COLOR", "red", ""},
+		{"display", "none", "important"},
+		{"background", "url(data:image/png;base64,AAAA) no-repeat", ""},
+		{"content", "'a;b:c'", ""},
+		{"margin", "", ""},
+		{"font-family", `"Open Sans", serif`, ""},
+		{"width", "", ""},
+		{"--Main-Color", "#fff", ""},
+		{"--main-color", "", ""},
+		{"visibility", "hidden", "important"},
+	}
+
+	style := dom.Style(doc.FirstChild)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := style.GetPropertyValue(tt.name); got != tt.wantValue {
+				t.Errorf("GetPropertyValue() = %v, want %v", got, tt.wantValue)
+			}
+
+			if got := style.GetPropertyPriority(tt.name); got != tt.wantPriority {
+				t.Errorf("GetPropertyPriority() = %v, want %v", got, tt.wantPriority)
+			}
+		})
+	}
+
+	wantNames := []string{"color", "display", "background", "content", "font-family", "--Main-Color", "visibility"}
+	if got := style.Length(); got != len(wantNames) {
+		t.Errorf("Length() = %v, want %v", got, len(wantNames))
+	}
+
+	for i, name := range wantNames {
+		if got := style.Item(i); got != name {
+			t.Errorf("Item(%d) = %v, want %v", i, got, name)
+		}
+	}
+}
+
+func TestStyleDuplicateProperty(t *testing.T) {
+	tests := []struct {
+		name       string
+		styleAttr  string
+		wantValue  string
+		wantCSSTxt string
+	}{{
+		name:       "last declaration wins",
+		styleAttr:  "color: red; color: blue",
+		wantValue:  "blue",
+		wantCSSTxt: "color: blue;",
+	}, {
+		name:       "important is not overridden by normal",
+		styleAttr:  "color: red !important; color: blue",
+		wantValue:  "red",
+		wantCSSTxt: "color: red !important;",
+	}, {
+		name:       "important is overridden by important",
+		styleAttr:  "color: red !important; color: blue !important",
+		wantValue:  "blue",
+		wantCSSTxt: "color: blue !important;",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := dom.CreateElement("div")
+			dom.SetAttribute(node, "style", tt.styleAttr)
+
+			style := dom.Style(node)
+			if got := style.GetPropertyValue("color"); got != tt.wantValue {
+				t.Errorf("GetPropertyValue() = %v, want %v", got, tt.wantValue)
+			}
+
+			if got := style.CSSText(); got != tt.wantCSSTxt {
+				t.Errorf("CSSText() = %v, want %v", got, tt.wantCSSTxt)
+			}
+		})
+	}
+}
+
+func TestStyleModify(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		modify     func(sd *dom.StyleDeclaration)
+		want       string
+	}{{
+		name:       "set new property",
+		htmlSource: `<p style="color:red"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("display", "none", "") },
+		want:       `<p style="color: red; display: none;"></p>`,
+	}, {
+		name:       "set property without style attribute",
+		htmlSource: `<p></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("display", "none", "important") },
+		want:       `<p style="display: none !important;"></p>`,
+	}, {
+		name:       "replace important property",
+		htmlSource: `<p style="display: none !important; color: red"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("Display", "block", "") },
+		want:       `<p style="color: red; display: block;"></p>`,
+	}, {
+		name:       "set invalid value is ignored",
+		htmlSource: `<p style="color: red"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("display", "none; color: blue", "") },
+		want:       `<p style="color: red"></p>`,
+	}, {
+		name:       "set invalid name is ignored",
+		htmlSource: `<p style="color: red"></p>`,
+		modify: func(sd *dom.StyleDeclaration) {
+			sd.SetProperty("display:none;color", "blue", "")
+			sd.SetProperty("a:b", "c", "")
+			sd.SetProperty(" margin", "0", "")
+		},
+		want: `<p style="color: red"></p>`,
+	}, {
+		name:       "set invalid priority is ignored",
+		htmlSource: `<p style="color: red"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("display", "none", "high") },
+		want:       `<p style="color: red"></p>`,
+	}, {
+		name:       "set empty value removes property",
+		htmlSource: `<p style="color: red; display: none"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetProperty("color", "", "") },
+		want:       `<p style="display: none;"></p>`,
+	}, {
+		name:       "remove property",
+		htmlSource: `<p style="color: red; /* note */ display: none"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.RemoveProperty("display") },
+		want:       `<p style="color: red;"></p>`,
+	}, {
+		name:       "remove property without style attribute",
+		htmlSource: `<p></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.RemoveProperty("display") },
+		want:       `<p></p>`,
+	}, {
+		name:       "set css text",
+		htmlSource: `<p style="color: red"></p>`,
+		modify:     func(sd *dom.StyleDeclaration) { sd.SetCSSText("margin:0;padding : 1px !important") },
+		want:       `<p style="margin: 0; padding: 1px !important;"></p>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("Style(), failed to parse: %v", err)
+			}
+
+			node := doc.FirstChild
+			tt.modify(dom.Style(node))
+			if got := dom.OuterHTML(node); got != tt.want {
+				t.Errorf("Style() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// RubyMode specifies how ruby annotation (e.g. the reading of Japanese kanji
+// in <ruby>漢字<rt>かんじ</rt></ruby>) is handled when extracting text.
+type RubyMode int
+
+const (
+	// RubyKeep keeps both the base text and the annotation as they
+	// are in the document, e.g. "漢字かんじ".
+	RubyKeep RubyMode = iota
+
+	// RubyBaseOnly drops the annotation and only keeps the base text, e.g. "漢字".
+	RubyBaseOnly
+
+	// RubyAnnotationOnly replaces the base text with its annotation, e.g. "かんじ".
+	// Base text that doesn't have annotation is kept as it is.
+	RubyAnnotationOnly
+
+	// RubyParenthesized puts the annotation inside parentheses
+	// after its base text, e.g. "漢字(かんじ)".
+	RubyParenthesized
+)
+
+// TextOptions is the options for extracting text from a node. The zero value
+// keeps the default behavior, i.e. TextContentWithOptions returns the same
+// text as TextContent, and InnerTextWithOptions the same text as InnerText.
+type TextOptions struct {
+	// Ruby specifies how ruby annotation is handled. The <rp> elements
+	// are always dropped unless it's RubyKeep.
+	Ruby RubyMode
+
+	// ExcludeTags is the list of tag names whose element will be excluded
+	// along with its descendants, e.g. "script" and "style".
+	ExcludeTags []string
+
+	// SkipHidden excludes text from hidden elements, using the same rules
+	// as InnerText. Only used by TextContentWithOptions.
+	SkipHidden bool
+
+	// CollapseWhitespace collapses each sequence of whitespace into a single
+	// space, and trims the whitespace at the start and the end of the text.
+	// Only used by TextContentWithOptions.
+	CollapseWhitespace bool
+
+	// BlockSeparator is the string to put between the text of block elements,
+	// e.g. "\n". The whitespace around the separator is dropped, even if the
+	// whitespace is not collapsed. Only used by TextContentWithOptions.
+	BlockSeparator string
+
+	// Cascade is used to decide which elements are hidden or block. If it's
+	// nil, only the inline style is used, like InnerText.
+	Cascade *Cascade
+}
+
+// TextContentWithOptions is like TextContent, but extracts the text
+// following the specified options. Comment is never included, since
+// only the text nodes are used to build the text.
+func TextContentWithOptions(node *html.Node, opts TextOptions) string {
+	w := textContentWriter{opts: opts}
+	if opts.SkipHidden || opts.BlockSeparator != "" {
+		w.cascade = cascadeFor(opts.Cascade)
+	}
+
+	w.writeNode(node)
+	if !w.pendingSeparator {
+		w.sb.WriteString(w.pendingWhitespace)
+	}
+	return w.sb.String()
+}
+
+// InnerTextWithOptions is like InnerText, but extracts the text
+// following the specified options.
+func InnerTextWithOptions(node *html.Node, opts TextOptions) string {
+	return renderedText(node, cascadeFor(opts.Cascade), opts)
+}
+
+// textContentWriter is the state for building text content with options.
+// The separator and the collapsed space are kept pending until the next
+// text is written, so they never appear at the start or the end of the text.
+// When whitespace is not collapsed, the whitespace around text is kept pending
+// as well, and dropped if it's at the boundary of block elements.
+type textContentWriter struct {
+	opts    TextOptions
+	cascade *Cascade
+	sb      strings.Builder
+
+	pendingSeparator  bool
+	pendingSpace      bool
+	pendingWhitespace string
+}
+
+func (w *textContentWriter) writeNode(node *html.Node) {
+	if node.Type == html.TextNode {
+		if w.opts.SkipHidden && w.cascade.ComputedStyle(node).Visibility != "visible" {
+			return
+		}
+
+		w.writeText(node.Data)
+		return
+	}
+
+	isBlock := false
+	if node.Type == html.ElementNode {
+		if isExcludedTag(node, w.opts) {
+			return
+		}
+
+		if w.cascade != nil {
+			display := w.cascade.ComputedStyle(node).Display
+			if w.opts.SkipHidden && display == "none" {
+				return
+			}
+			isBlock = w.opts.BlockSeparator != "" && isBlockLevelDisplay(display)
+		}
+	}
+
+	if isBlock {
+		w.pendingSeparator = true
+	}
+
+	if w.opts.Ruby == RubyKeep || !isHTMLElement(node, "ruby") {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			w.writeNode(child)
+		}
+	} else {
+		w.writeRuby(node)
+	}
+
+	if isBlock {
+		w.pendingSeparator = true
+	}
+}
+
+func (w *textContentWriter) writeRuby(ruby *html.Node) {
+	writeNodes := func(nodes []*html.Node) {
+		for _, node := range nodes {
+			w.writeNode(node)
+		}
+	}
+
+	for _, segment := range rubySegments(ruby) {
+		// Check the annotation text separately, so the empty
+		// annotation will be treated as no annotation
+		annotationWriter := textContentWriter{opts: w.opts, cascade: w.cascade}
+		for _, node := range segment.annotation {
+			annotationWriter.writeNode(node)
+		}
+		hasAnnotation := strings.TrimSpace(annotationWriter.sb.String()) != ""
+
+		switch {
+		case !hasAnnotation || w.opts.Ruby == RubyBaseOnly:
+			writeNodes(segment.base)
+		case w.opts.Ruby == RubyAnnotationOnly:
+			writeNodes(segment.annotation)
+		default:
+			writeNodes(segment.base)
+			w.writeText("(")
+			writeNodes(segment.annotation)
+			w.writeText(")")
+		}
+	}
+}
+
+func (w *textContentWriter) writeText(text string) {
+	if !w.opts.CollapseWhitespace {
+		// Whitespace around blocks is usually the indentation
+		// of the markup, so it's replaced by the separator
+		if w.opts.BlockSeparator != "" {
+			trimmed := strings.TrimLeftFunc(text, isASCIIWhitespace)
+			if !w.pendingSeparator {
+				w.pendingWhitespace += text[:len(text)-len(trimmed)]
+			}
+
+			content := strings.TrimRightFunc(trimmed, isASCIIWhitespace)
+			if content != "" {
+				w.flushPending()
+				w.sb.WriteString(content)
+				w.pendingWhitespace = trimmed[len(content):]
+			}
+			return
+		}
+
+		w.flushPending()
+		w.sb.WriteString(text)
+		return
+	}
+
+	fields := strings.FieldsFunc(text, isASCIIWhitespace)
+	if len(fields) == 0 {
+		if text != "" {
+			w.pendingSpace = true
+		}
+		return
+	}
+
+	if strings.IndexFunc(text[:1], isASCIIWhitespace) == 0 {
+		w.pendingSpace = true
+	}
+
+	for i, field := range fields {
+		if i > 0 {
+			w.pendingSpace = true
+		}
+		w.flushPending()
+		w.sb.WriteString(field)
+	}
+
+	if strings.LastIndexFunc(text, isASCIIWhitespace) == len(text)-1 {
+		w.pendingSpace = true
+	}
+}
+
+// flushPending writes the pending separator, collapsed space or whitespace.
+// The separator and the collapsed space are not written if it's still in
+// the start of the text, and the whitespace is dropped by the separator.
+func (w *textContentWriter) flushPending() {
+	switch {
+	case w.pendingSeparator && w.opts.BlockSeparator != "":
+		if w.sb.Len() > 0 {
+			w.sb.WriteString(w.opts.BlockSeparator)
+		}
+	case w.pendingWhitespace != "":
+		w.sb.WriteString(w.pendingWhitespace)
+	case w.pendingSpace && w.sb.Len() > 0:
+		w.sb.WriteByte(' ')
+	}
+
+	w.pendingSeparator = false
+	w.pendingSpace = false
+	w.pendingWhitespace = ""
+}
+
+// isExcludedTag check whether the element is excluded by the options.
+func isExcludedTag(node *html.Node, opts TextOptions) bool {
+	for _, tagName := range opts.ExcludeTags {
+		if strings.EqualFold(node.Data, tagName) {
+			return true
+		}
+	}
+	return false
+}
+
+// rubySegment is a base text in ruby element, paired with its annotation.
+type rubySegment struct {
+	base       []*html.Node
+	annotation []*html.Node
+}
+
+// rubySegments splits the children of a ruby element into segments of base
+// text and their annotation. A segment is ended by the <rt> or <rtc> elements,
+// and the <rp> elements are dropped.
+func rubySegments(ruby *html.Node) []rubySegment {
+	var segments []rubySegment
+	var current rubySegment
+
+	for child := ruby.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case isHTMLElement(child, "rp"):
+			continue
+		case isHTMLElement(child, "rt"):
+			current.annotation = append(current.annotation, child)
+		case isHTMLElement(child, "rtc"):
+			for rtcChild := child.FirstChild; rtcChild != nil; rtcChild = rtcChild.NextSibling {
+				if !isHTMLElement(rtcChild, "rp") {
+					current.annotation = append(current.annotation, rtcChild)
+				}
+			}
+		default:
+			if len(current.annotation) > 0 {
+				segments = append(segments, current)
+				current = rubySegment{}
+			}
+			current.base = append(current.base, child)
+		}
+	}
+
+	if len(current.base) > 0 || len(current.annotation) > 0 {
+		segments = append(segments, current)
+	}
+
+	return segments
+}
+
+// isHTMLElement check whether the node is an HTML element with the specified tag.
+func isHTMLElement(node *html.Node, tagName string) bool {
+	return node.Type == html.ElementNode && node.Namespace == "" && node.Data == tagName
+}
@@ -0,0 +1,220 @@
+package dom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestTextWithOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		htmlSource      string
+		mode            dom.RubyMode
+		wantTextContent string
+		wantInnerText   string
+	}{{
+		name:            "keep",
+		htmlSource:      "<div><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>を読む</div>",
+		mode:            dom.RubyKeep,
+		wantTextContent: "漢字(かんじ)を読む",
+		wantInnerText:   "漢字かんじを読む",
+	}, {
+		name:            "base only",
+		htmlSource:      "<div><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>を読む</div>",
+		mode:            dom.RubyBaseOnly,
+		wantTextContent: "漢字を読む",
+		wantInnerText:   "漢字を読む",
+	}, {
+		name:            "annotation only",
+		htmlSource:      "<div><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>を読む</div>",
+		mode:            dom.RubyAnnotationOnly,
+		wantTextContent: "かんじを読む",
+		wantInnerText:   "かんじを読む",
+	}, {
+		name:            "parenthesized",
+		htmlSource:      "<div><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>を読む</div>",
+		mode:            dom.RubyParenthesized,
+		wantTextContent: "漢字(かんじ)を読む",
+		wantInnerText:   "漢字(かんじ)を読む",
+	}, {
+		name:            "multiple segments",
+		htmlSource:      "<div><ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby></div>",
+		mode:            dom.RubyParenthesized,
+		wantTextContent: "漢(かん)字(じ)",
+		wantInnerText:   "漢(かん)字(じ)",
+	}, {
+		name:            "rb and rtc",
+		htmlSource:      "<div><ruby><rb>東</rb><rb>京</rb><rtc><rt>とう</rt><rt>きょう</rt></rtc></ruby></div>",
+		mode:            dom.RubyAnnotationOnly,
+		wantTextContent: "とうきょう",
+		wantInnerText:   "とうきょう",
+	}, {
+		name:            "base without annotation",
+		htmlSource:      "<div><ruby>漢字<rt></rt></ruby></div>",
+		mode:            dom.RubyAnnotationOnly,
+		wantTextContent: "漢字",
+		wantInnerText:   "漢字",
+	}, {
+		name:            "hidden annotation",
+		htmlSource:      `<div><ruby>漢字<rt style="display:none">かんじ</rt></ruby></div>`,
+		mode:            dom.RubyParenthesized,
+		wantTextContent: "漢字(かんじ)",
+		wantInnerText:   "漢字",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Fatalf("TextWithOptions(), failed to parse: %v", err)
+			}
+
+			div := dom.QuerySelector(doc, "div")
+			opts := dom.TextOptions{Ruby: tt.mode}
+
+			if got := dom.TextContentWithOptions(div, opts); got != tt.wantTextContent {
+				t.Errorf("TextContentWithOptions() = %q, want %q", got, tt.wantTextContent)
+			}
+
+			if got := dom.InnerTextWithOptions(div, opts); got != tt.wantInnerText {
+				t.Errorf("InnerTextWithOptions() = %q, want %q", got, tt.wantInnerText)
+			}
+		})
+	}
+}
+
+func TestTextContentWithOptions(t *testing.T) {
+	htmlSource := `<div>
+		<h1>Title</h1>
+		<script>var a = 1;</script>
+		<style>p { color: red; }</style>
+		<!-- comment -->
+		<p>First   <b>paragraph</b>
+		text</p>
+		<p style="display: none">Hidden</p>
+		<p hidden>Hidden attribute</p>
+		<p>Second <span style="visibility: hidden">invisible</span>paragraph</p>
+	</div>`
+
+	tests := []struct {
+		name string
+		opts dom.TextOptions
+		want string
+	}{{
+		name: "exclude tags",
+		opts: dom.TextOptions{
+			ExcludeTags:        []string{"script", "STYLE"},
+			CollapseWhitespace: true,
+		},
+		want: "Title First paragraph text Hidden Hidden attribute Second invisibleparagraph",
+	}, {
+		name: "skip hidden",
+		opts: dom.TextOptions{
+			ExcludeTags:        []string{"script", "style"},
+			SkipHidden:         true,
+			CollapseWhitespace: true,
+		},
+		want: "Title First paragraph text Second paragraph",
+	}, {
+		name: "block separator",
+		opts: dom.TextOptions{
+			ExcludeTags:        []string{"script", "style"},
+			SkipHidden:         true,
+			CollapseWhitespace: true,
+			BlockSeparator:     "\n",
+		},
+		want: "Title\nFirst paragraph text\nSecond paragraph",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(htmlSource)
+			if err != nil {
+				t.Fatalf("TextContentWithOptions(), failed to parse: %v", err)
+			}
+
+			div := dom.QuerySelector(doc, "div")
+			if got := dom.TextContentWithOptions(div, tt.opts); got != tt.want {
+				t.Errorf("TextContentWithOptions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("default is same as text content", func(t *testing.T) {
+		doc, err := parseHTMLSource(htmlSource)
+		if err != nil {
+			t.Fatalf("TextContentWithOptions(), failed to parse: %v", err)
+		}
+
+		div := dom.QuerySelector(doc, "div")
+		if got, want := dom.TextContentWithOptions(div, dom.TextOptions{}), dom.TextContent(div); got != want {
+			t.Errorf("TextContentWithOptions() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("block separator without collapsing", func(t *testing.T) {
+		doc, err := parseHTMLSource(`<div><p>Hello  world</p><p>Second <b>para</b></p>Tail</div>`)
+		if err != nil {
+			t.Fatalf("TextContentWithOptions(), failed to parse: %v", err)
+		}
+
+		div := dom.QuerySelector(doc, "div")
+		opts := dom.TextOptions{BlockSeparator: " | "}
+		if got, want := dom.TextContentWithOptions(div, opts), "Hello  world | Second para | Tail"; got != want {
+			t.Errorf("TextContentWithOptions() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("block separator with indented markup", func(t *testing.T) {
+		doc, err := parseHTMLSource("<div>\n  <p>Hello  <b>world</b> </p>\n  <p>Second</p>\n  Tail\n</div>")
+		if err != nil {
+			t.Fatalf("TextContentWithOptions(), failed to parse: %v", err)
+		}
+
+		div := dom.QuerySelector(doc, "div")
+		opts := dom.TextOptions{BlockSeparator: "\n"}
+		if got, want := dom.TextContentWithOptions(div, opts), "Hello  world\nSecond\nTail"; got != want {
+			t.Errorf("TextContentWithOptions() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("custom cascade", func(t *testing.T) {
+		htmlSource := `<link rel="stylesheet" href="main.css"><div><p>Hello</p><p class="ad">Ad</p></div>`
+		doc, err := html.Parse(strings.NewReader(htmlSource))
+		if err != nil {
+			t.Fatalf("InnerTextWithOptions(), failed to parse: %v", err)
+		}
+
+		cascade := dom.NewCascade(doc, dom.CascadeOptions{
+			ResolveStylesheet: func(href string) (string, bool) {
+				return ".ad { display: none }", href == "main.css"
+			},
+		})
+
+		div := dom.QuerySelector(doc, "div")
+		opts := dom.TextOptions{SkipHidden: true, Cascade: cascade}
+		if got, want := dom.TextContentWithOptions(div, opts), "Hello"; got != want {
+			t.Errorf("TextContentWithOptions() = %q, want %q", got, want)
+		}
+
+		if got, want := dom.InnerTextWithOptions(div, opts), "Hello"; got != want {
+			t.Errorf("InnerTextWithOptions() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("exclude tags in inner text", func(t *testing.T) {
+		doc, err := parseHTMLSource(`<div>Hello <code>fmt.Println()</code> world</div>`)
+		if err != nil {
+			t.Fatalf("InnerTextWithOptions(), failed to parse: %v", err)
+		}
+
+		div := dom.QuerySelector(doc, "div")
+		opts := dom.TextOptions{ExcludeTags: []string{"code"}}
+		if got, want := dom.InnerTextWithOptions(div, opts), "Hello world"; got != want {
+			t.Errorf("InnerTextWithOptions() = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package dom
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TokenList is a set of space-separated tokens that stored in an attribute,
+// similar with DOMTokenList in JS. It's a live view, so it always reads and
+// writes directly to the attribute of its node. Like in DOMTokenList, the
+// tokens are ordered and de-duplicated. Invalid token (i.e. empty or contains
+// whitespace) is ignored by the methods that modify the list.
+type TokenList struct {
+	node     *html.Node
+	attrName string
+}
+
+// NewTokenList returns a TokenList for the specified attribute of the node,
+// e.g. "rel" for <a> and <link>, or "sandbox" for <iframe>.
+func NewTokenList(node *html.Node, attrName string) *TokenList {
+	return &TokenList{
+		node:     node,
+		attrName: attrName,
+	}
+}
+
+// ClassList returns a TokenList for the class attribute of the node.
+func ClassList(node *html.Node) *TokenList {
+	return NewTokenList(node, "class")
+}
+
+// Length returns the number of tokens in the list.
+func (tl *TokenList) Length() int {
+	return len(tl.tokens())
+}
+
+// Item returns the token with the specified index, or empty string
+// if the index is out of range.
+func (tl *TokenList) Item(index int) string {
+	tokens := tl.tokens()
+	if index < 0 || index >= len(tokens) {
+		return ""
+	}
+	return tokens[index]
+}
+
+// Values returns all tokens in the list.
+func (tl *TokenList) Values() []string {
+	return tl.tokens()
+}
+
+// Contains returns a Boolean value indicating whether the
+// list contains the specified token.
+func (tl *TokenList) Contains(token string) bool {
+	return indexOfToken(tl.tokens(), token) >= 0
+}
+
+// Add adds the specified tokens to the list, omitting any that
+// are already present.
+func (tl *TokenList) Add(tokens ...string) {
+	if !validTokens(tokens...) {
+		return
+	}
+
+	currentTokens := tl.tokens()
+	for _, token := range tokens {
+		if indexOfToken(currentTokens, token) < 0 {
+			currentTokens = append(currentTokens, token)
+		}
+	}
+
+	tl.update(currentTokens)
+}
+
+// Remove removes the specified tokens from the list.
+func (tl *TokenList) Remove(tokens ...string) {
+	if !validTokens(tokens...) {
+		return
+	}
+
+	currentTokens := tl.tokens()
+	for _, token := range tokens {
+		if idx := indexOfToken(currentTokens, token); idx >= 0 {
+			currentTokens = append(currentTokens[:idx], currentTokens[idx+1:]...)
+		}
+	}
+
+	tl.update(currentTokens)
+}
+
+// Toggle removes the token from the list if it exists, or adds it to the list
+// if it doesn't. If force is specified, the token will only be added (if force
+// is true) or only be removed (if force is false). Returns true if the token is
+// in the list after the call.
+func (tl *TokenList) Toggle(token string, force ...bool) bool {
+	if !validTokens(token) {
+		return false
+	}
+
+	currentTokens := tl.tokens()
+	idx := indexOfToken(currentTokens, token)
+
+	if idx >= 0 {
+		if len(force) == 0 || !force[0] {
+			currentTokens = append(currentTokens[:idx], currentTokens[idx+1:]...)
+			tl.update(currentTokens)
+			return false
+		}
+		return true
+	}
+
+	if len(force) == 0 || force[0] {
+		currentTokens = append(currentTokens, token)
+		tl.update(currentTokens)
+		return true
+	}
+
+	return false
+}
+
+// Replace replaces an existing token with a new token. If the new token
+// already exists in the list, the old token is simply removed. Returns
+// true if the old token was successfully replaced.
+func (tl *TokenList) Replace(token string, newToken string) bool {
+	if !validTokens(token, newToken) {
+		return false
+	}
+
+	currentTokens := tl.tokens()
+	if indexOfToken(currentTokens, token) < 0 {
+		return false
+	}
+
+	// Put the new token in the first position of either the old
+	// or new token, then remove all the other occurrences
+	replaced := false
+	newTokens := make([]string, 0, len(currentTokens))
+	for _, currentToken := range currentTokens {
+		if currentToken != token && currentToken != newToken {
+			newTokens = append(newTokens, currentToken)
+		} else if !replaced {
+			newTokens = append(newTokens, newToken)
+			replaced = true
+		}
+	}
+
+	tl.update(newTokens)
+	return true
+}
+
+// Value returns the value of the underlying attribute.
+func (tl *TokenList) Value() string {
+	return GetAttribute(tl.node, tl.attrName)
+}
+
+// SetValue sets the value of the underlying attribute.
+func (tl *TokenList) SetValue(value string) {
+	SetAttribute(tl.node, tl.attrName, value)
+}
+
+// String returns the value of the underlying attribute.
+func (tl *TokenList) String() string {
+	return tl.Value()
+}
+
+// tokens parses the attribute value into ordered set of tokens.
+func (tl *TokenList) tokens() []string {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(tl.Value(), isASCIIWhitespace) {
+		if indexOfToken(tokens, token) < 0 {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// update writes the tokens back into attribute. If the attribute doesn't
+// exist and there are no tokens, the attribute won't be created.
+func (tl *TokenList) update(tokens []string) {
+	if !HasAttribute(tl.node, tl.attrName) && len(tokens) == 0 {
+		return
+	}
+
+	SetAttribute(tl.node, tl.attrName, strings.Join(tokens, " "))
+}
+
+// validTokens check whether all tokens are not empty and don't contain whitespace.
+func validTokens(tokens ...string) bool {
+	for _, token := range tokens {
+		if token == "" || strings.IndexFunc(token, isASCIIWhitespace) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfToken(tokens []string, token string) int {
+	for i := range tokens {
+		if tokens[i] == token {
+			return i
+		}
+	}
+	return -1
+}
+
+// isASCIIWhitespace check whether the rune is ASCII whitespace
+// as defined in WHATWG Infra standard.
+func isASCIIWhitespace(r rune) bool {
+	switch r {
+	case '\t', '\n', '\f', '\r', ' ':
+		return true
+	default:
+		return false
+	}
+}
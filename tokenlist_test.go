@@ -0,0 +1,200 @@
+package dom_test
+
+import (
+	"testing"
+
+	"github.com/go-shiori/dom"
+)
+
+func TestTokenListRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       []string
+	}{{
+		name:       "no attribute",
+		htmlSource: `<p></p>`,
+		want:       nil,
+	}, {
+		name:       "single class",
+		htmlSource: `<p class="title"></p>`,
+		want:       []string{"title"},
+	}, {
+		name:       "excess whitespace",
+		htmlSource: "<p class=\"  title\t\n main  \"></p>",
+		want:       []string{"title", "main"},
+	}, {
+		name:       "duplicate classes",
+		htmlSource: `<p class="a b a c b"></p>`,
+		want:       []string{"a", "b", "c"},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("TokenList(), failed to parse: %v", err)
+			}
+
+			classList := dom.ClassList(doc.FirstChild)
+			if got := classList.Length(); got != len(tt.want) {
+				t.Errorf("Length() = %v, want %v", got, len(tt.want))
+			}
+
+			for i, token := range tt.want {
+				if got := classList.Item(i); got != token {
+					t.Errorf("Item(%d) = %v, want %v", i, got, token)
+				}
+
+				if !classList.Contains(token) {
+					t.Errorf("Contains(%s) = false, want true", token)
+				}
+			}
+
+			if got := classList.Item(len(tt.want)); got != "" {
+				t.Errorf("Item(%d) = %v, want empty", len(tt.want), got)
+			}
+
+			if classList.Contains("unknown") {
+				t.Errorf("Contains(unknown) = true, want false")
+			}
+		})
+	}
+}
+
+func TestTokenListModify(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		modify     func(tl *dom.TokenList) bool
+		wantResult bool
+		want       string
+	}{{
+		name:       "add new tokens",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Add("b", "c"); return true },
+		wantResult: true,
+		want:       `<p class="a b c"></p>`,
+	}, {
+		name:       "add existing token normalizes value",
+		htmlSource: `<p class=" a  a b "></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Add("a"); return true },
+		wantResult: true,
+		want:       `<p class="a b"></p>`,
+	}, {
+		name:       "add invalid token is ignored",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Add("b", "c d"); return true },
+		wantResult: true,
+		want:       `<p class="a"></p>`,
+	}, {
+		name:       "remove tokens",
+		htmlSource: `<p class="a b c b"></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Remove("b", "x"); return true },
+		wantResult: true,
+		want:       `<p class="a c"></p>`,
+	}, {
+		name:       "remove from missing attribute doesn't create it",
+		htmlSource: `<p></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Remove("a"); return true },
+		wantResult: true,
+		want:       `<p></p>`,
+	}, {
+		name:       "remove last token keeps empty attribute",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { tl.Remove("a"); return true },
+		wantResult: true,
+		want:       `<p class=""></p>`,
+	}, {
+		name:       "toggle existing token",
+		htmlSource: `<p class="a b"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Toggle("a") },
+		wantResult: false,
+		want:       `<p class="b"></p>`,
+	}, {
+		name:       "toggle missing token",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Toggle("b") },
+		wantResult: true,
+		want:       `<p class="a b"></p>`,
+	}, {
+		name:       "toggle existing token with force true",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Toggle("a", true) },
+		wantResult: true,
+		want:       `<p class="a"></p>`,
+	}, {
+		name:       "toggle missing token with force false",
+		htmlSource: `<p class="a"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Toggle("b", false) },
+		wantResult: false,
+		want:       `<p class="a"></p>`,
+	}, {
+		name:       "replace token",
+		htmlSource: `<p class="a b c"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Replace("b", "x") },
+		wantResult: true,
+		want:       `<p class="a x c"></p>`,
+	}, {
+		name:       "replace token with existing token after it",
+		htmlSource: `<p class="a b c"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Replace("a", "c") },
+		wantResult: true,
+		want:       `<p class="c b"></p>`,
+	}, {
+		name:       "replace token with existing token before it",
+		htmlSource: `<p class="a b c"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Replace("c", "a") },
+		wantResult: true,
+		want:       `<p class="a b"></p>`,
+	}, {
+		name:       "replace missing token",
+		htmlSource: `<p class="a b"></p>`,
+		modify:     func(tl *dom.TokenList) bool { return tl.Replace("x", "y") },
+		wantResult: false,
+		want:       `<p class="a b"></p>`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Errorf("TokenList(), failed to parse: %v", err)
+			}
+
+			node := doc.FirstChild
+			if got := tt.modify(dom.ClassList(node)); got != tt.wantResult {
+				t.Errorf("TokenList() result = %v, want %v", got, tt.wantResult)
+			}
+
+			if got := dom.OuterHTML(node); got != tt.want {
+				t.Errorf("TokenList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenListOtherAttributes(t *testing.T) {
+	doc, err := parseHTMLSource(`<a rel="nofollow noopener"></a><iframe sandbox=""></iframe>`)
+	if err != nil {
+		t.Errorf("TokenList(), failed to parse: %v", err)
+	}
+
+	a := dom.QuerySelector(doc, "a")
+	relList := dom.NewTokenList(a, "rel")
+	relList.Replace("noopener", "noreferrer")
+	relList.Add("external")
+
+	want := `<a rel="nofollow noreferrer external"></a>`
+	if got := dom.OuterHTML(a); got != want {
+		t.Errorf("NewTokenList(rel) = %v, want %v", got, want)
+	}
+
+	iframe := dom.QuerySelector(doc, "iframe")
+	sandbox := dom.NewTokenList(iframe, "sandbox")
+	sandbox.Add("allow-scripts", "allow-same-origin")
+
+	if got := sandbox.Value(); got != "allow-scripts allow-same-origin" {
+		t.Errorf("NewTokenList(sandbox) = %v, want %v", got, "allow-scripts allow-same-origin")
+	}
+}
@@ -0,0 +1,278 @@
+package dom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrInvalidXMLName is returned by RenderXHTML when the name of element or
+// attribute can't be represented in XML, e.g. attribute "@click" which is
+// allowed in HTML, or an attribute with namespace prefix that never declared.
+var ErrInvalidXMLName = errors.New("dom: name can't be represented in XML")
+
+var (
+	// xhtmlTextEscaper escapes the text in XHTML.
+	xhtmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#13;")
+
+	// xhtmlAttrEscaper escapes the attribute value in XHTML. Tab and newline
+	// are escaped as well, since XML parser normalizes them into space.
+	xhtmlAttrEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"\t", "&#9;",
+		"\n", "&#10;",
+		"\r", "&#13;",
+	)
+)
+
+// RenderXHTML renders the node and its descendants as well-formed XHTML,
+// i.e. HTML in XML syntax. Elements are written in their namespace, which is
+// declared where it's changed (so <svg> and <math> declares their own), and
+// elements without content are self-closed. Boolean attributes are written
+// with their name as value, and the content of <script> and <style> is put
+// inside CDATA section when it contains markup characters. Characters that
+// not allowed in XML are replaced with U+FFFD, and comments that contain
+// "--" are broken with space. If the document is rendered, it's preceded by
+// XML declaration. Returns ErrInvalidXMLName if the name of element or
+// attribute can't be represented in XML.
+func RenderXHTML(w io.Writer, node *html.Node) error {
+	if node == nil {
+		return nil
+	}
+
+	x := xhtmlRenderer{rw: &renderWriter{w: w}}
+	scope := xmlScope{prefixes: map[string]string{"xml": XMLNamespace}}
+	x.writeNode(node, scope)
+	return x.rw.err
+}
+
+type xhtmlRenderer struct {
+	rw *renderWriter
+}
+
+// xmlScope is the namespaces that declared by the ancestors of an element.
+type xmlScope struct {
+	defaultNS string
+	prefixes  map[string]string
+}
+
+// declare returns a new scope with the prefix bound to the namespace.
+func (s xmlScope) declare(prefix, uri string) xmlScope {
+	prefixes := make(map[string]string, len(s.prefixes)+1)
+	for p, u := range s.prefixes {
+		prefixes[p] = u
+	}
+	prefixes[prefix] = uri
+	return xmlScope{defaultNS: s.defaultNS, prefixes: prefixes}
+}
+
+func (x *xhtmlRenderer) writeNode(node *html.Node, scope xmlScope) {
+	switch node.Type {
+	case html.ErrorNode:
+		x.rw.setError(errors.New("dom: cannot render an ErrorNode node"))
+
+	case html.TextNode:
+		text := toXMLChars(node.Data)
+		if isHTMLElement(node.Parent, "script") || isHTMLElement(node.Parent, "style") {
+			if strings.ContainsAny(text, "<&") || strings.Contains(text, "]]>") {
+				// "]]>" can't be inside CDATA, so it's split into two sections
+				text = strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>")
+				x.rw.writeString("<![CDATA[" + text + "]]>")
+			} else {
+				x.rw.writeString(text)
+			}
+			return
+		}
+		x.rw.writeString(xhtmlTextEscaper.Replace(text))
+
+	case html.DocumentNode:
+		x.rw.writeString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			x.writeNode(child, scope)
+		}
+
+	case html.ElementNode:
+		x.writeElement(node, scope)
+
+	case html.CommentNode:
+		x.rw.writeString("<!--" + xmlComment(node.Data) + "-->")
+
+	case html.DoctypeNode:
+		// The public and system identifiers are dropped, since
+		// XHTML document only uses the HTML doctype.
+		if !isXMLName(node.Data) {
+			x.rw.setError(fmt.Errorf("%w: doctype %q", ErrInvalidXMLName, node.Data))
+			return
+		}
+		x.rw.writeString("<!DOCTYPE " + node.Data + ">\n")
+
+	default:
+		x.rw.writeVerbatim(node)
+	}
+}
+
+func (x *xhtmlRenderer) writeElement(node *html.Node, scope xmlScope) {
+	if !isXMLName(node.Data) {
+		x.rw.setError(fmt.Errorf("%w: element %q", ErrInvalidXMLName, node.Data))
+		return
+	}
+
+	var declarations, attributes []string
+	if uri := NamespaceURI(node); uri != scope.defaultNS {
+		declarations = append(declarations, `xmlns="`+xhtmlAttrEscaper.Replace(uri)+`"`)
+		scope.defaultNS = uri
+	}
+
+	// Declare the prefixes that explicitly declared in the element
+	declare := func(prefix, uri string) {
+		if scope.prefixes[prefix] != uri {
+			declarations = append(declarations, "xmlns:"+prefix+`="`+xhtmlAttrEscaper.Replace(toXMLChars(uri))+`"`)
+			scope = scope.declare(prefix, uri)
+		}
+	}
+
+	for _, attr := range node.Attr {
+		prefix, localName := attr.Namespace, attr.Key
+		if prefix == "" {
+			prefix, localName = splitQualifiedName(attr.Key)
+		}
+
+		if prefix == "xmlns" && isXMLName(localName) && localName != "xml" && localName != "xmlns" {
+			declare(localName, attr.Val)
+		}
+	}
+
+	for _, attr := range node.Attr {
+		prefix, localName := attr.Namespace, attr.Key
+		if prefix == "" {
+			prefix, localName = splitQualifiedName(attr.Key)
+		}
+
+		switch {
+		case prefix == "" && localName == "xmlns":
+			// The default namespace is declared from the element's namespace
+			continue
+		case prefix == "xmlns":
+			if isXMLName(localName) && localName != "xml" && localName != "xmlns" {
+				continue
+			}
+		case prefix != "" && attr.Namespace != "":
+			// Attribute from the parser that has known namespace, e.g. xlink:href
+			declare(prefix, attributeNamespaceURI(attr))
+		}
+
+		_, declared := scope.prefixes[prefix]
+		if !isXMLName(localName) || (prefix != "" && (!isXMLName(prefix) || !declared)) {
+			name := attributeQualifiedName(attr)
+			x.rw.setError(fmt.Errorf("%w: attribute %q of <%s>", ErrInvalidXMLName, name, node.Data))
+			return
+		}
+
+		value := attr.Val
+		if _, isBoolean := booleanAttributes[attr.Key]; isBoolean &&
+			value == "" && node.Namespace == "" && attr.Namespace == "" {
+			value = attr.Key
+		}
+
+		name := localName
+		if prefix != "" {
+			name = prefix + ":" + localName
+		}
+		attributes = append(attributes, name+`="`+xhtmlAttrEscaper.Replace(toXMLChars(value))+`"`)
+	}
+
+	x.rw.writeString("<" + node.Data)
+	for _, attr := range append(declarations, attributes...) {
+		x.rw.writeString(" " + attr)
+	}
+
+	if node.FirstChild == nil && (node.Namespace != "" || IsVoidElement(node)) {
+		x.rw.writeString(" />")
+		return
+	}
+	x.rw.writeString(">")
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		x.writeNode(child, scope)
+	}
+	x.rw.writeString(endTag(node))
+}
+
+// xmlComment returns the comment text that allowed in XML,
+// i.e. it doesn't contain "--" and doesn't end with "-".
+func xmlComment(data string) string {
+	data = toXMLChars(data)
+	for strings.Contains(data, "--") {
+		data = strings.ReplaceAll(data, "--", "- -")
+	}
+
+	if strings.HasSuffix(data, "-") {
+		data += " "
+	}
+	return data
+}
+
+// toXMLChars replaces the characters that not allowed in XML with U+FFFD.
+func toXMLChars(text string) string {
+	isValid := func(r rune) bool {
+		return r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || r >= 0x10000
+	}
+
+	if strings.IndexFunc(text, func(r rune) bool { return !isValid(r) }) < 0 {
+		return text
+	}
+
+	return strings.Map(func(r rune) rune {
+		if isValid(r) {
+			return r
+		}
+		return '\uFFFD'
+	}, text)
+}
+
+// isXMLName check whether the name is a valid XML name without namespace
+// prefix, i.e. NCName in Namespaces in XML specification.
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		if !isXMLNameStartChar(r) && (i == 0 || !isXMLNameChar(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isXMLNameStartChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		return true
+	case r >= 0xC0 && r <= 0xD6, r >= 0xD8 && r <= 0xF6, r >= 0xF8 && r <= 0x2FF,
+		r >= 0x370 && r <= 0x37D, r >= 0x37F && r <= 0x1FFF, r >= 0x200C && r <= 0x200D,
+		r >= 0x2070 && r <= 0x218F, r >= 0x2C00 && r <= 0x2FEF, r >= 0x3001 && r <= 0xD7FF,
+		r >= 0xF900 && r <= 0xFDCF, r >= 0xFDF0 && r <= 0xFFFD, r >= 0x10000 && r <= 0xEFFFF:
+		return true
+	default:
+		return false
+	}
+}
+
+func isXMLNameChar(r rune) bool {
+	switch {
+	case r == '-', r == '.', r >= '0' && r <= '9', r == 0xB7:
+		return true
+	case r >= 0x300 && r <= 0x36F, r >= 0x203F && r <= 0x2040:
+		return true
+	default:
+		return isXMLNameStartChar(r)
+	}
+}
@@ -0,0 +1,159 @@
+package dom_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-shiori/dom"
+	"golang.org/x/net/html"
+)
+
+func TestRenderXHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		htmlSource string
+		want       string
+	}{{
+		name:       "void and boolean attributes",
+		htmlSource: `<p>A<br>B<img src=a.png alt=""><input type=checkbox checked disabled></p>`,
+		want:       `<p>A<br />B<img src="a.png" alt="" /><input type="checkbox" checked="checked" disabled="disabled" /></p>`,
+	}, {
+		name:       "empty non-void element",
+		htmlSource: `<div></div><p title="a&quot;b<c>">1 &lt; 2 &amp;&amp; 3 &gt; 2</p>`,
+		want:       `<div></div><p title="a&quot;b&lt;c&gt;">1 &lt; 2 &amp;&amp; 3 &gt; 2</p>`,
+	}, {
+		name:       "script and style",
+		htmlSource: `x<script>if (a < b && c) {}</script><script>var x = 1;</script><style>a > b { color: red }</style>`,
+		want:       `x<script><![CDATA[if (a < b && c) {}]]></script><script>var x = 1;</script><style>a > b { color: red }</style>`,
+	}, {
+		name:       "CDATA end inside script",
+		htmlSource: `x<script>x = a[b[0]]>1 && y;</script>`,
+		want:       `x<script><![CDATA[x = a[b[0]]]]><![CDATA[>1 && y;]]></script>`,
+	}, {
+		name:       "svg and mathml",
+		htmlSource: `<svg viewBox="0 0 10 10"><circle r=5 /><a xlink:href="#x">link</a></svg><math><mi>x</mi></math>`,
+		want: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><circle r="5" />` +
+			`<a xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="#x">link</a></svg>` +
+			`<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi></math>`,
+	}, {
+		name:       "svg with declared xlink",
+		htmlSource: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/></svg>`,
+		want: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">` +
+			`<use xlink:href="#a" /></svg>`,
+	}, {
+		name:       "foreign object",
+		htmlSource: `<svg><foreignObject><p>text</p></foreignObject></svg>`,
+		want: `<svg xmlns="http://www.w3.org/2000/svg"><foreignObject>` +
+			`<p xmlns="http://www.w3.org/1999/xhtml">text</p></foreignObject></svg>`,
+	}, {
+		name:       "comments",
+		htmlSource: `x<!-- a -- b --><!--x--->`,
+		want:       `x<!-- a - - b --><!--x- -->`,
+	}, {
+		name:       "invalid characters",
+		htmlSource: "<p title=\"a\x01b\">c\x0Bd</p>",
+		want:       "<p title=\"a�b\">c�d</p>",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := parseHTMLSource(tt.htmlSource)
+			if err != nil {
+				t.Fatalf("RenderXHTML(), failed to parse: %v", err)
+			}
+
+			var sb strings.Builder
+			if err := dom.RenderXHTML(&sb, body); err != nil {
+				t.Fatalf("RenderXHTML() error = %v", err)
+			}
+
+			want := `<body xmlns="http://www.w3.org/1999/xhtml">` + tt.want + `</body>`
+			if got := sb.String(); got != want {
+				t.Errorf("RenderXHTML() = %q, want %q", got, want)
+			}
+
+			if err := checkWellFormedXML(sb.String()); err != nil {
+				t.Errorf("RenderXHTML() is not well-formed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderXHTMLDocument(t *testing.T) {
+	htmlSource := `<!DOCTYPE html><html lang="en" xmlns:epub="http://www.idpf.org/2007/ops">` +
+		`<head><meta charset="utf-8"><title>Title</title></head>` +
+		`<body><section epub:type="chapter"><p xml:lang="fr">Bonjour</p></section></body></html>`
+
+	doc, err := dom.Parse(strings.NewReader(htmlSource))
+	if err != nil {
+		t.Fatalf("RenderXHTML(), failed to parse: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := dom.RenderXHTML(&sb, doc); err != nil {
+		t.Fatalf("RenderXHTML() error = %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!DOCTYPE html>\n" +
+		`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en">` +
+		`<head><meta charset="utf-8" /><title>Title</title></head>` +
+		`<body><section epub:type="chapter"><p xml:lang="fr">Bonjour</p></section></body></html>`
+
+	if got := sb.String(); got != want {
+		t.Errorf("RenderXHTML() = %q, want %q", got, want)
+	}
+
+	if err := checkWellFormedXML(sb.String()); err != nil {
+		t.Errorf("RenderXHTML() is not well-formed: %v", err)
+	}
+}
+
+func TestRenderXHTMLInvalidName(t *testing.T) {
+	tests := []struct {
+		name string
+		node func() *html.Node
+	}{{
+		name: "invalid element name",
+		node: func() *html.Node { return dom.CreateElement("my:tag") },
+	}, {
+		name: "invalid attribute name",
+		node: func() *html.Node {
+			div := dom.CreateElement("div")
+			dom.SetAttribute(div, "@click", "go()")
+			return div
+		},
+	}, {
+		name: "undeclared prefix",
+		node: func() *html.Node {
+			div := dom.CreateElement("div")
+			dom.SetAttribute(div, "epub:type", "chapter")
+			return div
+		},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dom.RenderXHTML(io.Discard, tt.node())
+			if !errors.Is(err, dom.ErrInvalidXMLName) {
+				t.Errorf("RenderXHTML() error = %v, want %v", err, dom.ErrInvalidXMLName)
+			}
+		})
+	}
+}
+
+func checkWellFormedXML(source string) error {
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}